@@ -0,0 +1,210 @@
+package srcco
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// If OutDir ends in .tar.gz (or .tgz) or .zip, we put the docs in an
+// archive with that name instead of a directory, for artifact stores
+// and the like. Each file goes into the archive as soon as it's
+// written, so we never hold the whole site in memory. Building the
+// same docs twice should give you the same archive, byte for byte, so
+// that you can tell when they've changed: every file has archiveTime
+// as its modification time, and genDocs writes the pages in the order
+// of the files (rather than whenever they're ready) when it's writing
+// to an archive.
+
+// archiveTime is the modification time of every file in an archive.
+// It's the earliest time that a zip file can hold.
+var archiveTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// archiveKind returns the kind of archive, "tar.gz" or "zip", that
+// the output name calls for, or "" for a directory.
+func archiveKind(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(name, ".zip"):
+		return "zip"
+	}
+	return ""
+}
+
+// An ArchiveOutput is an Output that puts the docs in an archive,
+// writing each file into it as it comes. An archive can't take a file
+// back, so it's an error to write the same file twice, or to remove
+// one. It starts out empty, so every page is generated every time.
+type ArchiveOutput struct {
+	mu      sync.Mutex
+	w       archiveWriter
+	written map[string]bool
+}
+
+// An archiveWriter writes the files that make up an archive, one
+// after another.
+type archiveWriter interface {
+	add(name string, data []byte) error
+	close() error
+}
+
+// TarGzOutput returns an ArchiveOutput that writes a gzipped tarball
+// to w.
+func TarGzOutput(w io.Writer) *ArchiveOutput {
+	// The zero gzip.Header has no name and no modification time,
+	// which is what we want.
+	zw := gzip.NewWriter(w)
+	return &ArchiveOutput{w: &tarGzWriter{zw, tar.NewWriter(zw)}}
+}
+
+// ZipOutput returns an ArchiveOutput that writes a zip file to w.
+func ZipOutput(w io.Writer) *ArchiveOutput {
+	return &ArchiveOutput{w: zipWriter{zip.NewWriter(w)}}
+}
+
+// Open always fails, because the archive starts out empty and we can't
+// read back what we've written to it.
+func (a *ArchiveOutput) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (a *ArchiveOutput) WriteFile(name string, data []byte) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.written[name] {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrExist}
+	}
+	if a.written == nil {
+		a.written = map[string]bool{}
+	}
+	a.written[name] = true
+	return a.w.add(name, data)
+}
+
+func (a *ArchiveOutput) Remove(name string) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for f := range a.written {
+		if f == name || name == "." || strings.HasPrefix(f, name+"/") {
+			return &fs.PathError{Op: "remove", Path: name, Err: errors.New("can't remove a file from an archive")}
+		}
+	}
+	return nil
+}
+
+// Close finishes the archive. It doesn't close the underlying writer.
+func (a *ArchiveOutput) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.w.close()
+}
+
+type tarGzWriter struct {
+	zw *gzip.Writer
+	tw *tar.Writer
+}
+
+func (w *tarGzWriter) add(name string, data []byte) error {
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  archiveTime,
+		Format:   tar.FormatPAX,
+	}
+	if err := w.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := w.tw.Write(data)
+	return err
+}
+
+func (w *tarGzWriter) close() error {
+	if err := w.tw.Close(); err != nil {
+		return err
+	}
+	return w.zw.Close()
+}
+
+type zipWriter struct {
+	zw *zip.Writer
+}
+
+func (w zipWriter) add(name string, data []byte) error {
+	fw, err := w.zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: archiveTime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(data)
+	return err
+}
+
+func (w zipWriter) close() error {
+	return w.zw.Close()
+}
+
+// genArchive generates the docs for files in the archive OutDir, which
+// is of the given kind (see archiveKind).
+func (g *generator) genArchive(ctx context.Context, p provider, files []string, kind string) error {
+	name := g.projectPath(g.OutDir)
+	// We write the archive next to where it's going, and only
+	// move it into place once it's all there, so that a run that
+	// fails (or is interrupted) doesn't leave half an archive
+	// where the last good one was.
+	w, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(w.Name())
+	defer w.Close()
+	out := TarGzOutput(w)
+	if kind == "zip" {
+		out = ZipOutput(w)
+	}
+	g.vLogf("Creating archive %s", name)
+	err = g.genDocs(ctx, p, out, files)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	// If KeepGoing skipped some of the files, we still put the
+	// archive in place, just like we'd leave the pages we could
+	// generate in a directory. Anything else leaves the last
+	// archive alone.
+	var pe *problemsError
+	if err != nil && !errors.As(err, &pe) {
+		return err
+	}
+	// CreateTemp makes the file only readable by us, but an
+	// archive should be like any other file we write.
+	if cerr := os.Chmod(w.Name(), 0644); cerr != nil {
+		return cerr
+	}
+	if rerr := os.Rename(w.Name(), name); rerr != nil {
+		return rerr
+	}
+	return err
+}
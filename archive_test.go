@@ -0,0 +1,117 @@
+package srcco
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tarNames returns the names of the files in the gzipped tarball b, in
+// order.
+func tarNames(t *testing.T, b []byte) []string {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(zr)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return names
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if !hdr.ModTime.Equal(archiveTime) {
+			t.Errorf("%s has the modification time %v, want %v", hdr.Name, hdr.ModTime, archiveTime)
+		}
+		names = append(names, hdr.Name)
+	}
+}
+
+func TestGenArchive(t *testing.T) {
+	outDir := t.TempDir()
+	// OutDir can be outside the project.
+	name := filepath.Join(outDir, "docs.tar.gz")
+	gen := func() []byte {
+		t.Helper()
+		g := testGenerator(t, Options{Dir: testDir(t, testSources), OutDir: name, Jobs: 4})
+		a := testAnalysis(t)
+		if err := g.genArchive(context.Background(), a, a.files, "tar.gz"); err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	first := gen()
+	names := tarNames(t, first)
+	// The pages go in in the order of the files, whichever of
+	// them was ready first.
+	if len(names) < 2 || names[0] != "greet/greet.go.html" || names[1] != "main.go.html" {
+		t.Errorf("the archive starts with %v, want the pages in order", names)
+	}
+	if second := gen(); !bytes.Equal(first, second) {
+		t.Error("generating the same docs twice made different archives")
+	}
+	fis, err := ioutil.ReadDir(outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fis) != 1 {
+		t.Errorf("got %d files next to the archive, want just the archive", len(fis))
+	}
+	if fis[0].Mode().Perm() != 0644 {
+		t.Errorf("the archive has mode %v, want 0644", fis[0].Mode().Perm())
+	}
+}
+
+// A run that fails leaves the last archive where it was.
+func TestGenArchiveFails(t *testing.T) {
+	dir := testDir(t, testSources)
+	name := filepath.Join(dir, "docs.zip")
+	if err := ioutil.WriteFile(name, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	g := testGenerator(t, Options{Dir: dir, OutDir: "docs.zip"})
+	a := testAnalysis(t)
+	if err := os.Remove(filepath.Join(dir, "main.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.genArchive(context.Background(), a, a.files, "zip"); err == nil {
+		t.Fatal("generated the docs without main.go")
+	}
+	if b, err := ioutil.ReadFile(name); err != nil || string(b) != "old" {
+		t.Errorf("the archive is %q (err = %v), want the old one", b, err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, ".docs.zip.tmp-*"))
+	if len(matches) != 0 {
+		t.Errorf("left temporary files behind: %v", matches)
+	}
+}
+
+func TestArchiveOutputWriteTwice(t *testing.T) {
+	var b bytes.Buffer
+	out := ZipOutput(&b)
+	if err := out.WriteFile("a.html", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.WriteFile("a.html", []byte("b")); err == nil {
+		t.Error("wrote a.html to the archive twice")
+	}
+	if err := out.Remove("a.html"); err == nil {
+		t.Error("removed a.html from the archive")
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,180 @@
+package srcco
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ann links every ref to its def, but it's just as handy to go the
+// other way, and see everywhere a def is used. We turn the refs we
+// load for each file around into a map from defs to the places that
+// refer to them, and write it out next to the pages, one file for
+// each file of defs. srcco.js adds a "N refs" button next to each def
+// on a page, and srcco-refs.html lists the refs to one def.
+//
+// The refs to a def can change without the def's own page changing,
+// so we don't put them in the page itself: that way, the manifest
+// only has to worry about the refs in a file, and not the refs to it.
+
+// backlinksDirName is the directory in the output directory for the
+// refs to the defs in each file. The refs to the defs in "a/b.go" go
+// in "srcco-refs/a/b.go.json".
+const backlinksDirName = "srcco-refs"
+
+// backlinksPageName is the page that lists the refs to a def.
+const backlinksPageName = "srcco-refs.html"
+
+// A refSite is a place where a file refers to a def in the project.
+// Line is the line number of the ref, Row is the segment it's in (so
+// we can link to it) and Text is the line itself. The manifest keeps
+// the sites for each page, like it does the text.
+type refSite struct {
+	Def  defKey
+	Line int    `json:"l"`
+	Row  int    `json:"r"`
+	Text string `json:"t"`
+}
+
+// refSites finds the sites of the refs in rs, which must be sorted. We
+// only care about refs to the defs in defsMap. Every def is also a ref
+// to itself, which isn't much of a reference, so we leave out the
+// first ref to a def inside its own declaration.
+func refSites(src []byte, rs []ref, segments []segment, defsMap map[defKey]def) []refSite {
+	var sites []refSite
+	self := map[defKey]bool{}
+	lineStarts := []int{0}
+	for i, c := range src {
+		if c == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	for _, r := range rs {
+		k := defKey{r.DefUnit, r.DefPath}
+		d, ok := defsMap[k]
+		if !ok {
+			continue
+		}
+		if !self[k] && d.File == r.File && d.DefStart <= r.Start && r.Start < d.DefEnd {
+			self[k] = true
+			continue
+		}
+		start := int(r.Start)
+		line := sort.Search(len(lineStarts), func(i int) bool { return lineStarts[i] > start })
+		lineStart, lineEnd := lineStarts[line-1], len(src)
+		if i := bytes.IndexByte(src[lineStart:], '\n'); i >= 0 {
+			lineEnd = lineStart + i
+		}
+		sites = append(sites, refSite{
+			Def:  k,
+			Line: line,
+			Row:  segmentAt(segments, start),
+			Text: strings.TrimSpace(string(src[lineStart:lineEnd])),
+		})
+	}
+	return sites
+}
+
+// segmentAt returns the index of the segment whose code has the byte
+// at offset in it.
+func segmentAt(segments []segment, offset int) int {
+	row := 0
+	for i, s := range segments {
+		if s.CodeHTML == "" {
+			continue
+		}
+		if s.start > offset {
+			break
+		}
+		row = i
+	}
+	return row
+}
+
+// A backlinks file has the refs to each def in a file, keyed by the
+// id of the def's anchor.
+type backlinks map[string]*backlinksDef
+
+type backlinksDef struct {
+	Name string     `json:"name"`
+	Kind string     `json:"kind"`
+	Refs []backlink `json:"refs"`
+}
+
+type backlink struct {
+	File string `json:"f"`
+	Line int    `json:"l"`
+	Row  int    `json:"r"`
+	Text string `json:"t"`
+}
+
+// writeBacklinks writes the backlinks for every file of defs to out.
+// sites[i] holds the ref sites in files[i]. We start from scratch
+// every time, so that we don't leave backlinks behind for files that
+// went away.
+func writeBacklinks(out Output, files []string, sites [][]refSite, defsMap map[defKey]def) error {
+	if err := out.Remove(backlinksDirName); err != nil {
+		return err
+	}
+	byFile := map[string]backlinks{}
+	for i, f := range files {
+		for _, s := range sites[i] {
+			d, ok := defsMap[s.Def]
+			if !ok {
+				continue
+			}
+			bl := byFile[d.File]
+			if bl == nil {
+				bl = backlinks{}
+				byFile[d.File] = bl
+			}
+			id := d.id()
+			bd := bl[id]
+			if bd == nil {
+				name := d.Name
+				if name == "" {
+					name = path.Base(d.Path)
+				}
+				bd = &backlinksDef{Name: name, Kind: d.Kind}
+				bl[id] = bd
+			}
+			bd.Refs = append(bd.Refs, backlink{
+				File: filepath.ToSlash(f),
+				Line: s.Line,
+				Row:  s.Row,
+				Text: s.Text,
+			})
+		}
+	}
+	defFiles := make([]string, 0, len(byFile))
+	for f := range byFile {
+		defFiles = append(defFiles, f)
+	}
+	sort.Strings(defFiles)
+	for _, f := range defFiles {
+		b, err := json.Marshal(byFile[f])
+		if err != nil {
+			return err
+		}
+		if err := out.WriteFile(backlinksDirName+"/"+filepath.ToSlash(f)+".json", b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBacklinksPage writes the page that lists the refs to a def to
+// out, using the template t. Like the search page, srcco.js fills it
+// in: the def comes from the "file" and "def" parameters in the URL.
+func writeBacklinksPage(out Output, t *template.Template, fileTOC string) error {
+	var b bytes.Buffer
+	data := HTMLOutput{Title: "References", FileTableOfContents: template.HTML(fileTOC)}
+	if err := t.Execute(&b, data); err != nil {
+		return err
+	}
+	return out.WriteFile(backlinksPageName, b.Bytes())
+}
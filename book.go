@@ -0,0 +1,299 @@
+package srcco
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"html"
+	"html/template"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// A book is the whole project on one long page, with a chapter for
+// each file, which is a nice way to read a project from start to
+// finish when you're new to it. Every link to a def goes to its
+// anchor on the same page (see defHref), and the outline panel lists
+// the chapters, with each file's defs nested underneath.
+//
+// The chapters come in the order of the file names, unless BookOrder
+// says otherwise: "deps" puts packages after the packages they import
+// (see depsOrder), so that you read about things before you see them
+// used, and a file name means the files listed in that file (see
+// listedOrder).
+
+// bookPageName is the name of the book in the output directory.
+const bookPageName = "index.html"
+
+// BookOutput is fed into the book template. Like HTMLOutput, the bits
+// that are already HTML have the type template.HTML.
+type BookOutput struct {
+	Title           string
+	TableOfContents template.HTML
+	Chapters        []BookChapter
+}
+
+// A BookChapter is one file in a book. ID is the id of the chapter's
+// heading.
+type BookChapter struct {
+	Title    string
+	ID       string
+	Segments []segment
+}
+
+// chapterID is the id of the heading of the chapter for file.
+func chapterID(file string) string {
+	return "chapter-" + filepath.ToSlash(file)
+}
+
+// bookFormatter is the formatter for -format=book. Since the book has
+// everything on one page, it doesn't keep a manifest: we generate the
+// whole book every time.
+type bookFormatter struct {
+	g        *generator
+	out      Output
+	files    []string
+	fileDefs [][]def
+	defsMap  map[defKey]def
+	// chapters[i] is the chapter for files[i]. Its ID is empty
+	// if we had to skip the file.
+	chapters []BookChapter
+}
+
+func (r *bookFormatter) start(files []string, fileDefs [][]def, defsMap map[defKey]def) error {
+	r.files, r.fileDefs, r.defsMap = files, fileDefs, defsMap
+	r.chapters = make([]BookChapter, len(files))
+	return nil
+}
+
+func (r *bookFormatter) reuse(i int, src []byte) bool { return false }
+
+func (r *bookFormatter) page(i int, src []byte, pg *page) error {
+	f := r.files[i]
+	r.chapters[i] = BookChapter{Title: f, ID: chapterID(f), Segments: pg.segments}
+	return nil
+}
+
+func (r *bookFormatter) finish() error {
+	// Files that we had to skip don't get a chapter.
+	var book BookOutput
+	var outlines []string
+	for i, c := range r.chapters {
+		if c.ID == "" {
+			continue
+		}
+		book.Chapters = append(book.Chapters, c)
+		sortDefs(r.fileDefs[i])
+		outlines = append(outlines, createOutline(defsTOCFilter(r.fileDefs[i])))
+	}
+	book.Title = filepath.Base(r.g.Dir)
+	book.TableOfContents = template.HTML(createBookTOC(book.Chapters, outlines))
+
+	r.g.vLogf("Creating file %s", bookPageName)
+	var b bytes.Buffer
+	if err := r.g.assets.book.Execute(&b, book); err != nil {
+		return err
+	}
+	if err := r.out.WriteFile(bookPageName, b.Bytes()); err != nil {
+		return err
+	}
+	// The search box and the hover cards work in a book too, so
+	// it needs the symbol index.
+	r.g.vLogf("Creating file %s", symbolIndexName)
+	if err := writeSymbolIndex(r.out, r.defsMap); err != nil {
+		return err
+	}
+	return writeAssets(r.out, r.g.assets)
+}
+
+// createBookTOC creates the outline panel for a book: a node for each
+// chapter, with the outline of the chapter's defs underneath it.
+// srcco.js treats the chapters like defs, so the panel highlights the
+// chapter that you're reading, as well as the def.
+func createBookTOC(chapters []BookChapter, outlines []string) string {
+	var b bytes.Buffer
+	b.WriteString(`<div class="outline"><div class="outline-group"><div class="outline-kind">chapters</div><ul>`)
+	for i, c := range chapters {
+		class := "outline-node"
+		if outlines[i] != "" {
+			class += " collapsed"
+		}
+		fmt.Fprintf(&b, `<li class="%s"><div class="outline-title">`, class)
+		if outlines[i] != "" {
+			b.WriteString(`<i class="fa fa-angle-right outline-toggle"></i>`)
+		} else {
+			b.WriteString(`<i class="outline-leaf"></i>`)
+		}
+		fmt.Fprintf(&b, `<a href="#%s" data-def="%s">%s</a></div>`, html.EscapeString(c.ID), html.EscapeString(c.ID), html.EscapeString(c.Title))
+		b.WriteString(outlines[i])
+		b.WriteString(`</li>`)
+	}
+	b.WriteString(`</ul></div></div>`)
+	return b.String()
+}
+
+// bookOrder puts files in the order that BookOrder asks for.
+func (g *generator) bookOrder(files []string) ([]string, error) {
+	switch g.BookOrder {
+	case "":
+		return files, nil
+	case "deps":
+		return g.depsOrder(files), nil
+	}
+	return g.listedOrder(files)
+}
+
+// listedOrder puts files in the order that they're listed in the file
+// BookOrder, and leaves out the ones that aren't listed. Each line of
+// the list is a file name relative to the project, with slashes, or a
+// pattern like "cmd/*.go" (see path.Match) for all of the files that
+// match it, in the order of their names. Blank lines and lines that
+// start with "#" don't count.
+func (g *generator) listedOrder(files []string) ([]string, error) {
+	list := g.BookOrder
+	if !filepath.IsAbs(list) {
+		list = filepath.Join(g.Dir, list)
+	}
+	b, err := ioutil.ReadFile(list)
+	if err != nil {
+		return nil, err
+	}
+	var ordered []string
+	added := map[string]bool{}
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pattern := path.Clean(line)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s:%d: bad pattern %q: %v", g.BookOrder, n, line, err)
+		}
+		found := false
+		for _, f := range files {
+			if ok, _ := path.Match(pattern, filepath.ToSlash(f)); ok {
+				found = true
+				if !added[f] {
+					ordered = append(ordered, f)
+					added[f] = true
+				}
+			}
+		}
+		if !found {
+			g.logf("warning: %s:%d: there's no file %s in the project", g.BookOrder, n, line)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(ordered) == 0 {
+		return nil, fmt.Errorf("%s doesn't list any of the files in the project", g.BookOrder)
+	}
+	return ordered, nil
+}
+
+// depsOrder sorts files by directory, putting the directories of Go
+// packages after the directories of the packages they import. Files in
+// the same directory stay in the order they were in, and directories
+// that don't depend on each other (including the ones without Go
+// code) are in the order of their names.
+//
+// We only read the imports, so this works whatever the backend is. To
+// know which imports are in the project, we need its import path,
+// which comes from go.mod. Without one (in a GOPATH project, say), we
+// guess that an import whose path ends with a directory's name is
+// that directory.
+func (g *generator) depsOrder(files []string) []string {
+	byDir := map[string][]string{}
+	var dirs []string
+	for _, f := range files {
+		d := filepath.Dir(f)
+		if _, ok := byDir[d]; !ok {
+			dirs = append(dirs, d)
+		}
+		byDir[d] = append(byDir[d], f)
+	}
+	sort.Strings(dirs)
+
+	var modPath string
+	if b, err := ioutil.ReadFile(filepath.Join(g.Dir, "go.mod")); err == nil {
+		modPath = modfile.ModulePath(b)
+	}
+	// dirOf returns the directory in the project that has the
+	// package with the import path p.
+	dirOf := func(p string) (string, bool) {
+		for _, d := range dirs {
+			slash := filepath.ToSlash(d)
+			switch {
+			case modPath != "" && d == ".":
+				if p == modPath {
+					return d, true
+				}
+			case modPath != "":
+				if p == modPath+"/"+slash {
+					return d, true
+				}
+			case d != ".":
+				if p == slash || strings.HasSuffix(p, "/"+slash) {
+					return d, true
+				}
+			}
+		}
+		return "", false
+	}
+
+	deps := map[string][]string{}
+	fset := token.NewFileSet()
+	for _, f := range files {
+		if filepath.Ext(f) != ".go" {
+			continue
+		}
+		af, err := parser.ParseFile(fset, filepath.Join(g.Dir, f), nil, parser.ImportsOnly)
+		if err != nil {
+			g.vLogf("Can't read the imports in %s: %v", f, err)
+			continue
+		}
+		d := filepath.Dir(f)
+		for _, imp := range af.Imports {
+			p, err := strconv.Unquote(imp.Path.Value)
+			if err != nil {
+				continue
+			}
+			if dep, ok := dirOf(p); ok && dep != d {
+				deps[d] = append(deps[d], dep)
+			}
+		}
+	}
+
+	// We visit the directories depth first, so each one comes
+	// after everything it imports. Go doesn't allow import
+	// cycles, but tests can make them (and so can a broken
+	// project), so we don't visit a directory twice.
+	var ordered []string
+	seen := map[string]bool{}
+	var visit func(d string)
+	visit = func(d string) {
+		if seen[d] {
+			return
+		}
+		seen[d] = true
+		sort.Strings(deps[d])
+		for _, dep := range deps[d] {
+			visit(dep)
+		}
+		ordered = append(ordered, byDir[d]...)
+	}
+	for _, d := range dirs {
+		visit(d)
+	}
+	return ordered
+}
@@ -0,0 +1,105 @@
+// Command srcco generates literate-programming-style documentation
+// for a project. It's a thin wrapper around the srcco package (see
+// sourcegraph.com/sourcegraph/srcco), which does all of the work.
+//
+//	Usage: srcco [FLAGS] DIR
+//	       srcco serve [FLAGS] DIR
+//
+//	Generate documentation for the project at DIR.
+//	With "serve", serve it and regenerate it when DIR changes.
+//
+//	  -backend="srclib": the analysis backend: "srclib" runs the src CLI, "go" analyzes Go code in-process
+//	  -book-order="": the order of the chapters in a book: "deps" for package dependency order, or a file that lists the files
+//	  -dry-run=false: show what -github-pages would commit and push, without doing it
+//	  -enable-sourcegraph=false: generate links to Sourcegraph.com for references to external (out of repo) definitions
+//	  -force=false: regenerate every page, even the ones that haven't changed since the last run
+//	  -format="html": the kind of docs to generate: "html" for a page per file, "book" for one page with a chapter per file, "markdown" for a Markdown page per file, "json" for the rendered pages as data
+//	  -github-pages=false: create docs in gh-pages branch
+//	  -http=":8080": the address that "srcco serve" listens on
+//	  -index="": read defs, refs and docs from this SCIP (.scip) or LSIF (.lsif) index instead of running a backend
+//	  -j=NumCPU: the number of files to process at once
+//	  -keep-going=false: keep going when a file can't be generated, and list the problems at the end
+//	  -out="docs": the directory name for the output files, or an archive name ending in .tar.gz, .tgz or .zip
+//	  -push=true: push the gh-pages branch after -github-pages commits to it
+//	  -remote="origin": the git remote that -github-pages pushes to
+//	  -template-dir="": a directory of templates, srcco.css and srcco.js to use in place of the built-in ones
+//	  -theme="": the colors of the pages: "light", "dark", "high-contrast", "docco", or the path of a stylesheet to use in place of srcco.css
+//	  -v=false: show verbose output
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+
+	"sourcegraph.com/sourcegraph/srcco"
+)
+
+// We define our option flags here. They fill in the fields of opts
+// directly; httpOpt is the only one that isn't an option for
+// srcco.Generate.
+var (
+	opts    = srcco.Options{Log: os.Stderr}
+	httpOpt string
+)
+
+func init() {
+	flag.BoolVar(&opts.Verbose, "v", false, "show verbose output")
+	flag.StringVar(&opts.OutDir, "out", "docs", "the directory name for the output files, or an archive name ending in .tar.gz, .tgz or .zip")
+	flag.BoolVar(&opts.GitHubPages, "github-pages", false, "create docs in gh-pages branch and push to GitHub")
+	flag.StringVar(&opts.Remote, "remote", "origin", "the git remote that -github-pages pushes to")
+	flag.BoolVar(&opts.Push, "push", true, "push the gh-pages branch after -github-pages commits to it")
+	flag.BoolVar(&opts.DryRun, "dry-run", false, "show what -github-pages would commit and push, without doing it")
+	flag.BoolVar(&opts.EnableSourcegraphLinks, "enable-sourcegraph", false, "generate links to Sourcegraph.com for references to external (out of repo) definitions")
+	flag.IntVar(&opts.Jobs, "j", runtime.NumCPU(), "the number of files to process at once")
+	flag.StringVar(&opts.Index, "index", "", "read defs, refs and docs from this SCIP (.scip) or LSIF (.lsif) index instead of running a backend (files modified after it only get a warning)")
+	flag.StringVar(&opts.Backend, "backend", "srclib", "the analysis backend: \"srclib\" runs the src CLI, \"go\" analyzes Go code in-process")
+	flag.BoolVar(&opts.KeepGoing, "keep-going", false, "keep going when a file can't be generated, and list the problems at the end")
+	flag.StringVar(&opts.Format, "format", "html", "the kind of docs to generate: \"html\" for a page per file, \"book\" for one page with a chapter per file, \"markdown\" for a Markdown page per file, \"json\" for the rendered pages as data")
+	flag.StringVar(&opts.BookOrder, "book-order", "", "the order of the chapters in a book: \"deps\" for package dependency order, or a file that lists the files")
+	flag.BoolVar(&opts.Force, "force", false, "regenerate every page, even the ones that haven't changed since the last run")
+	flag.StringVar(&opts.TemplateDir, "template-dir", "", "a directory of templates, srcco.css and srcco.js to use in place of the built-in ones")
+	flag.StringVar(&opts.Theme, "theme", "", "the colors of the pages: \"light\", \"dark\", \"high-contrast\", \"docco\", or the path of a stylesheet to use in place of srcco.css")
+	flag.StringVar(&httpOpt, "http", ":8080", "the address that \"srcco serve\" listens on")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: srcco [FLAGS] DIR\n")
+		fmt.Fprintf(os.Stderr, "       srcco serve [FLAGS] DIR\n")
+		fmt.Fprintf(os.Stderr, "Generate documentation for the project at DIR.\n")
+		fmt.Fprintf(os.Stderr, "With \"serve\", serve it and regenerate it when DIR changes.\n")
+		fmt.Fprintf(os.Stderr, "For more information, see:\n")
+		fmt.Fprintf(os.Stderr, "\tsourcegraph.github.io/srcco\n")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+}
+
+func main() {
+	log.SetFlags(0)
+	// "srcco serve DIR" previews the docs. The flags come after
+	// "serve", so we parse them ourselves.
+	run := srcco.Generate
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		run = func(ctx context.Context, opts srcco.Options) error {
+			return srcco.Serve(ctx, opts, httpOpt)
+		}
+		flag.CommandLine.Parse(os.Args[2:])
+	} else {
+		flag.Parse()
+	}
+	args := flag.Args()
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "error: must provide a root directory\n")
+		flag.Usage()
+	} else if len(args) > 1 {
+		fmt.Fprintf(os.Stderr, "error: too many args\n")
+		flag.Usage()
+	}
+	opts.Dir = args[0]
+	if err := run(context.Background(), opts); err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+}
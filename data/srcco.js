@@ -35,8 +35,112 @@ window.onload = function () {
             }
         }
     });
+    setUpOutline();
+    setUpSearch();
+    setUpTextSearch();
+    setUpBacklinks();
+    setUpBacklinksPage();
+    setUpHoverCards();
+    var hidden = document.querySelectorAll(".code-hidden");
+    for (var i = 0; i < hidden.length; i++) {
+        hidden[i].addEventListener("click", function(ev) {
+            var row = closestClass(ev.target, "row");
+            row.classList.add("shown");
+            var code = row.querySelector(".code");
+            code.style.height = "auto";
+        });
+    }
 };
 
+// setUpOutline makes the nodes in the outline of defs collapsible, and
+// keeps the def that's at the top of the window highlighted as you
+// scroll (a "scroll-spy").
+function setUpOutline() {
+    var outline = document.getElementById("outline");
+    if (!outline) {
+        return;
+    }
+    var toggles = outline.querySelectorAll(".outline-toggle");
+    for (var i = 0; i < toggles.length; i++) {
+        toggles[i].addEventListener("click", function(ev) {
+            closestClass(ev.target, "outline-node").classList.toggle("collapsed");
+        });
+    }
+
+    // Each link in the outline goes with a def's anchor in the
+    // code. We remember where the anchors are, in the order they
+    // appear on the page, and work it out again if the page
+    // changes size.
+    var spies = [];
+    var links = outline.querySelectorAll("a[data-def]");
+    for (var i = 0; i < links.length; i++) {
+        var anchor = document.getElementById(links[i].getAttribute("data-def"));
+        if (anchor) {
+            spies.push({anchor: anchor, link: links[i], top: 0});
+        }
+    }
+    var measure = function() {
+        for (var i = 0; i < spies.length; i++) {
+            spies[i].top = spies[i].anchor.getBoundingClientRect().top + window.pageYOffset;
+        }
+        spies.sort(function(a, b) { return a.top - b.top; });
+    };
+
+    // The current def is the last one that starts above the first
+    // quarter of the window.
+    var current;
+    var update = function() {
+        var y = window.pageYOffset + window.innerHeight / 4;
+        var link;
+        for (var i = 0; i < spies.length && spies[i].top <= y; i++) {
+            link = spies[i].link;
+        }
+        if (link === current) {
+            return;
+        }
+        if (current) {
+            current.classList.remove("current");
+        }
+        current = link;
+        if (link) {
+            link.classList.add("current");
+            revealInOutline(outline, link);
+        }
+    };
+
+    var pending = false;
+    var schedule = function() {
+        if (!pending) {
+            pending = true;
+            window.requestAnimationFrame(function() {
+                pending = false;
+                update();
+            });
+        }
+    };
+    window.addEventListener("scroll", schedule);
+    window.addEventListener("resize", function() {
+        measure();
+        schedule();
+    });
+    measure();
+    update();
+}
+
+// revealInOutline opens the nodes above link, and scrolls the outline
+// so that you can see it.
+function revealInOutline(outline, link) {
+    for (var n = link.parentNode; n && n !== outline; n = n.parentNode) {
+        if (n.classList.contains("outline-node") && n.querySelector(".outline-title") !== link.parentNode) {
+            n.classList.remove("collapsed");
+        }
+    }
+    var top = link.offsetTop, bottom = top + link.offsetHeight;
+    if (top < outline.scrollTop || bottom > outline.scrollTop + outline.clientHeight) {
+        outline.scrollTop = top - outline.clientHeight / 2;
+    }
+}
+
 function closestClass(elem, className) {
     // Get closest match
     for (; elem && elem !== document; elem = elem.parentNode) {
@@ -117,3 +221,607 @@ function triggerNode(node) {
         deactivateAll(body.parentNode);
     }
 }
+
+// setUpSearch hooks up the search box. The symbol index (see
+// search.go) can be big, so we don't fetch it until someone starts
+// typing. Press "/" to get to the search box, the arrow keys to pick
+// a result, Enter to go to it and Escape to give up.
+function setUpSearch() {
+    var input = document.getElementById("search");
+    var list = document.getElementById("search-results");
+    if (!input) {
+        return;
+    }
+    var prefix = document.body.getAttribute("data-resource-prefix") || "";
+    // In a book (see book.go), every def is on this page, and
+    // there's no full-text search.
+    var book = document.body.hasAttribute("data-book");
+    var symbols;
+    var results = [], selected = 0;
+
+    var load = function() {
+        loadSymbols(prefix, function(syms) {
+            symbols = syms.list;
+            update();
+        });
+    };
+
+    var show = function() {
+        list.innerHTML = "";
+        for (var i = 0; i < results.length; i++) {
+            var li = document.createElement("li");
+            li.className = i === selected ? "search-result selected" : "search-result";
+            if (results[i].sym) {
+                li.innerHTML = results[i].html + ' <span class="search-where">' +
+                    escapeHTML(results[i].sym.kind + " in " + results[i].sym.file.replace(/\.html$/, "")) + "</span>";
+            } else {
+                li.innerHTML = '<span class="search-where">Search comments and code for</span> ' + escapeHTML(results[i].text);
+            }
+            li.setAttribute("data-index", i);
+            li.addEventListener("mousedown", function(ev) {
+                ev.preventDefault();
+                go(parseInt(closestClass(ev.target, "search-result").getAttribute("data-index"), 10));
+            });
+            list.appendChild(li);
+        }
+        var li = list.children[selected];
+        if (li) {
+            if (li.offsetTop < list.scrollTop) {
+                list.scrollTop = li.offsetTop;
+            } else if (li.offsetTop + li.offsetHeight > list.scrollTop + list.clientHeight) {
+                list.scrollTop = li.offsetTop + li.offsetHeight - list.clientHeight;
+            }
+        }
+    };
+
+    var update = function() {
+        results = [];
+        selected = 0;
+        var q = input.value.trim();
+        if (q && symbols) {
+            for (var i = 0; i < symbols.length; i++) {
+                var m = fuzzyMatch(q, symbols[i].name);
+                if (m) {
+                    results.push({sym: symbols[i], score: m.score, html: m.html});
+                }
+            }
+            results.sort(function(a, b) {
+                return b.score - a.score || a.sym.name.length - b.sym.name.length;
+            });
+            results = results.slice(0, 50);
+        }
+        // The last result is always a full-text search for the
+        // query, in case it isn't the name of a def.
+        if (q && !book) {
+            results.push({text: q});
+        }
+        show();
+    };
+
+    var go = function(i) {
+        var r = results[i];
+        if (!r) {
+            return;
+        }
+        input.value = "";
+        update();
+        input.blur();
+        if (!r.sym) {
+            window.location.href = prefix + "srcco-search.html?q=" + encodeURIComponent(r.text);
+            return;
+        }
+        var hash = "#" + encodeURIComponent(r.sym.anchor).replace(/%2F/g, "/");
+        window.location.href = book ? hash : prefix + r.sym.file + hash;
+    };
+
+    input.addEventListener("focus", load);
+    input.addEventListener("input", function() {
+        load();
+        update();
+    });
+    input.addEventListener("blur", function() {
+        results = [];
+        show();
+    });
+    input.addEventListener("keydown", function(ev) {
+        switch (ev.key) {
+        case "ArrowDown":
+            selected = Math.min(selected + 1, results.length - 1);
+            show();
+            break;
+        case "ArrowUp":
+            selected = Math.max(selected - 1, 0);
+            show();
+            break;
+        case "Enter":
+            go(selected);
+            break;
+        case "Escape":
+            input.value = "";
+            input.blur();
+            break;
+        default:
+            return;
+        }
+        ev.preventDefault();
+    });
+    document.addEventListener("keydown", function(ev) {
+        var t = ev.target.tagName;
+        if (ev.key === "/" && t !== "INPUT" && t !== "TEXTAREA") {
+            ev.preventDefault();
+            input.focus();
+        }
+    });
+}
+
+// loadSymbols fetches the symbol index (see search.go) the first time
+// it's called, and calls fn with it once it's here. fn gets the
+// symbols as a list, and as a map from their anchors.
+var symbolsLoaded, symbolsWaiting;
+
+function loadSymbols(prefix, fn) {
+    if (symbolsLoaded) {
+        fn(symbolsLoaded);
+        return;
+    }
+    if (symbolsWaiting) {
+        symbolsWaiting.push(fn);
+        return;
+    }
+    symbolsWaiting = [fn];
+    var req = new XMLHttpRequest();
+    req.open("GET", prefix + "srcco-symbols.json");
+    req.onload = function() {
+        if (req.status !== 200 && req.status !== 0) {
+            symbolsWaiting = undefined;
+            return;
+        }
+        var list = JSON.parse(req.responseText), byAnchor = {};
+        for (var i = 0; i < list.length; i++) {
+            byAnchor[list[i].anchor] = list[i];
+        }
+        symbolsLoaded = {list: list, byAnchor: byAnchor};
+        var waiting = symbolsWaiting;
+        symbolsWaiting = undefined;
+        for (var i = 0; i < waiting.length; i++) {
+            waiting[i](symbolsLoaded);
+        }
+    };
+    req.onerror = function() {
+        symbolsWaiting = undefined;
+    };
+    req.send();
+}
+
+// setUpHoverCards shows a card with the kind, signature and doc of a
+// def when you rest the mouse on a link to it. ann marks the links
+// with data-def, and the cards come from the symbol index.
+function setUpHoverCards() {
+    var prefix = document.body.getAttribute("data-resource-prefix") || "";
+    var card, timer, link;
+    var hide = function() {
+        clearTimeout(timer);
+        link = undefined;
+        if (card) {
+            card.parentNode.removeChild(card);
+            card = undefined;
+        }
+    };
+    document.addEventListener("mouseover", function(ev) {
+        var a = ev.target.closest ? ev.target.closest("a[data-def]") : null;
+        if (a === link) {
+            return;
+        }
+        hide();
+        if (!a) {
+            return;
+        }
+        link = a;
+        // We wait a moment, so that the cards don't flash up
+        // as you move the mouse across the code.
+        timer = setTimeout(function() {
+            loadSymbols(prefix, function(syms) {
+                var sym = syms.byAnchor[a.getAttribute("data-def")];
+                if (a !== link || !sym) {
+                    return;
+                }
+                card = document.createElement("div");
+                card.className = "hover-card";
+                var html = '<div class="hover-card-kind">' + escapeHTML(sym.kind) + " " + escapeHTML(sym.name) + "</div>";
+                if (sym.sig) {
+                    html += '<pre class="hover-card-sig">' + escapeHTML(sym.sig) + "</pre>";
+                }
+                if (sym.doc) {
+                    html += '<div class="hover-card-doc">' + escapeHTML(sym.doc) + "</div>";
+                }
+                card.innerHTML = html;
+                var r = a.getBoundingClientRect();
+                card.style.top = (r.bottom + window.pageYOffset + 4) + "px";
+                card.style.left = (r.left + window.pageXOffset) + "px";
+                document.body.appendChild(card);
+            });
+        }, 300);
+    });
+    window.addEventListener("scroll", hide);
+}
+
+// setUpTextSearch runs the full-text search on srcco-search.html. The
+// query comes from the "q" parameter in the URL. We look up each word
+// of the query in the index (see fulltext.go) and show the lines that
+// have all of them. The last word can be the start of a word, so
+// that "gen" finds "genDocs" too. The index only says where the lines
+// are, so we fetch the pages that they're on to show them.
+function setUpTextSearch() {
+    var page = document.getElementById("text-search");
+    if (!page) {
+        return;
+    }
+    var out = document.getElementById("text-search-results");
+    var input = page.querySelector("input[name=q]");
+    var q = queryParam("q").trim();
+    input.value = q;
+    if (!q) {
+        input.focus();
+        return;
+    }
+    document.title = q + " - Search";
+    out.textContent = "Searching...";
+    var prefix = document.body.getAttribute("data-resource-prefix") || "";
+    var req = new XMLHttpRequest();
+    req.open("GET", prefix + "srcco-search.json");
+    req.onload = function() {
+        if (req.status !== 200 && req.status !== 0) {
+            out.textContent = "Couldn't load the search index.";
+            return;
+        }
+        var idx = JSON.parse(req.responseText);
+        if (idx.version !== 2) {
+            out.textContent = "The search index is from a different version of srcco.";
+            return;
+        }
+        showTextResults(out, idx, q, prefix);
+    };
+    req.onerror = function() {
+        out.textContent = "Couldn't load the search index.";
+    };
+    req.send();
+}
+
+// textSearch returns the lines in idx that have every word in words,
+// in order, as {p, r, n} (the page, the row, and the line in the row).
+// The last word only needs to start a word in the line. idx.words is
+// sorted, so the words that start with it are all together.
+function textSearch(idx, words) {
+    var found;
+    for (var i = 0; i < words.length; i++) {
+        var lines = {};
+        var last = i === words.length - 1;
+        for (var k = firstWord(idx.words, words[i]); k < idx.words.length; k++) {
+            var w = idx.words[k];
+            if (w !== words[i] && !(last && w.indexOf(words[i]) === 0)) {
+                break;
+            }
+            var ps = idx.postings[k];
+            for (var j = 0; j < ps.length; j += 3) {
+                var key = ps[j] + ":" + ps[j + 1] + ":" + ps[j + 2];
+                if (!found || found[key]) {
+                    lines[key] = {p: ps[j], r: ps[j + 1], n: ps[j + 2]};
+                }
+            }
+        }
+        found = lines;
+    }
+    var ls = [];
+    for (var l in found) {
+        ls.push(found[l]);
+    }
+    return ls.sort(function(a, b) { return a.p - b.p || a.r - b.r || a.n - b.n; });
+}
+
+// firstWord returns the index of the first word in words (which are
+// sorted) that isn't less than w.
+function firstWord(words, w) {
+    var lo = 0, hi = words.length;
+    while (lo < hi) {
+        var mid = (lo + hi) >> 1;
+        if (words[mid] < w) {
+            lo = mid + 1;
+        } else {
+            hi = mid;
+        }
+    }
+    return lo;
+}
+
+function showTextResults(out, idx, q, prefix) {
+    var words = q.toLowerCase().split(/[^\w\u00c0-\uffff]+/).filter(function(w) { return w.length > 1; });
+    var all = words.length ? textSearch(idx, words) : [];
+    var total = all.length, limit = 500;
+    var lines = all.slice(0, limit);
+    var pages = [];
+    for (var i = 0; i < lines.length; i++) {
+        if (!i || lines[i].p !== lines[i - 1].p) {
+            pages.push(lines[i].p);
+        }
+    }
+    fetchPageLines(idx, pages, prefix, function(rows) {
+        var html = "<p>" + total + (total === 1 ? " line matches " : " lines match ") +
+            "<b>" + escapeHTML(q) + "</b>" + (total > limit ? " (showing the first " + limit + ")" : "") + ".</p>";
+        var re = new RegExp("(" + words.map(function(w) { return w.replace(/[.*+?^${}()|[\]\\]/g, "\\$&"); }).join("|") + ")", "gi");
+        var page = -1;
+        for (var i = 0; i < lines.length; i++) {
+            var l = lines[i];
+            if (l.p !== page) {
+                if (page !== -1) {
+                    html += "</ul>";
+                }
+                page = l.p;
+                html += '<h3 class="text-search-file"><a href="' + pageURL(prefix, idx.pages[page]) + '">' +
+                    escapeHTML(idx.pages[page].replace(/\.html$/, "")) + "</a></h3><ul>";
+            }
+            // The page could have changed since the index was
+            // made, or not have loaded at all, in which case we
+            // can still link to the row.
+            var row = rows[l.p] && rows[l.p][l.r];
+            var line = row && row[l.n];
+            var text = "row " + (l.r + 1);
+            if (line) {
+                // Splitting on a regexp with a group leaves the
+                // matches at the odd indexes.
+                var parts = line.text.split(re);
+                text = "";
+                for (var j = 0; j < parts.length; j++) {
+                    text += j % 2 ? "<b>" + escapeHTML(parts[j]) + "</b>" : escapeHTML(parts[j]);
+                }
+            }
+            html += '<li class="' + (line && line.doc ? "text-search-doc" : "text-search-code") + '"><a href="' +
+                pageURL(prefix, idx.pages[l.p], "row-" + l.r) + '">' + text + "</a></li>";
+        }
+        if (page !== -1) {
+            html += "</ul>";
+        }
+        out.innerHTML = html;
+    });
+}
+
+// fetchPageLines fetches the pages in pages (indexes into idx.pages)
+// and calls done with the lines of their rows, as rows[page][row][n],
+// where each line is {text, doc}. We break the rows into lines the same
+// way that segmentLines does (see fulltext.go), so that the numbers in
+// the index point at the right ones.
+function fetchPageLines(idx, pages, prefix, done) {
+    var rows = {}, left = pages.length;
+    if (!left) {
+        done(rows);
+        return;
+    }
+    var finish = function() {
+        if (--left === 0) {
+            done(rows);
+        }
+    };
+    pages.forEach(function(p) {
+        var req = new XMLHttpRequest();
+        req.open("GET", prefix + idx.pages[p].split("/").map(encodeURIComponent).join("/"));
+        req.onload = function() {
+            if (req.status === 200 || req.status === 0) {
+                rows[p] = rowLines(new DOMParser().parseFromString(req.responseText, "text/html"));
+            }
+            finish();
+        };
+        req.onerror = finish;
+        req.send();
+    });
+}
+
+// rowLines returns the non-blank lines of each row in doc, the doc's
+// first and then the code's.
+function rowLines(doc) {
+    var rows = [];
+    for (var i = 0; ; i++) {
+        var row = doc.getElementById("row-" + i);
+        if (!row) {
+            return rows;
+        }
+        var lines = [];
+        var add = function(el, isDoc) {
+            if (!el) {
+                return;
+            }
+            el.textContent.split("\n").forEach(function(l) {
+                l = isDoc ? l.trim() : l.replace(/\s+$/, "");
+                if (l) {
+                    lines.push({text: l, doc: isDoc});
+                }
+            });
+        };
+        add(row.querySelector(".doc"), true);
+        add(row.querySelector(".code"), false);
+        rows.push(lines);
+    }
+}
+
+// setUpBacklinks adds a button next to each def on a code page that
+// has refs, which shows where the def is used. The refs to the defs
+// in a file are in srcco-refs/FILE.json (see backlinks.go).
+function setUpBacklinks() {
+    var file = document.body.getAttribute("data-file");
+    if (!file) {
+        return;
+    }
+    var prefix = document.body.getAttribute("data-resource-prefix") || "";
+    var req = new XMLHttpRequest();
+    req.open("GET", prefix + "srcco-refs/" + file + ".json");
+    req.onload = function() {
+        if (req.status !== 200 && req.status !== 0) {
+            return;
+        }
+        var defs = JSON.parse(req.responseText);
+        for (var id in defs) {
+            var anchor = document.getElementById(id);
+            if (anchor) {
+                addBacklinksButton(anchor, id, defs[id], file, prefix);
+            }
+        }
+    };
+    req.send();
+
+    // Clicking anywhere else closes the popover.
+    document.addEventListener("click", function(ev) {
+        if (!closestClass(ev.target, "backlinks-popover") && !closestClass(ev.target, "backlinks-button")) {
+            closeBacklinks();
+        }
+    });
+}
+
+function addBacklinksButton(anchor, id, def, file, prefix) {
+    var n = def.refs.length;
+    var button = document.createElement("span");
+    button.className = "backlinks-button";
+    button.title = "Referenced from " + n + (n === 1 ? " place" : " places");
+    button.textContent = n + (n === 1 ? " ref" : " refs");
+    button.addEventListener("click", function(ev) {
+        var open = document.querySelector(".backlinks-popover");
+        closeBacklinks();
+        if (open && open.getAttribute("data-def") === id) {
+            return;
+        }
+        // The code rows hide anything that overflows them, so
+        // the popover goes on the body, under the button.
+        var pop = document.createElement("div");
+        pop.className = "backlinks-popover";
+        pop.setAttribute("data-def", id);
+        var limit = 20;
+        var html = "<div>Referenced from " + n + (n === 1 ? " place" : " places") + "</div><ul>";
+        for (var i = 0; i < n && i < limit; i++) {
+            html += backlinkHTML(def.refs[i], prefix);
+        }
+        html += '</ul><a class="backlinks-all" href="' + backlinksPageURL(prefix, file, id) + '">' +
+            (n > limit ? "See all " + n + " references" : "See them on their own page") + "</a>";
+        pop.innerHTML = html;
+        var r = button.getBoundingClientRect();
+        pop.style.top = (r.bottom + window.pageYOffset + 4) + "px";
+        pop.style.left = Math.max(0, r.right + window.pageXOffset - 400) + "px";
+        document.body.appendChild(pop);
+    });
+    anchor.parentNode.insertBefore(button, anchor);
+}
+
+function closeBacklinks() {
+    var pops = document.querySelectorAll(".backlinks-popover");
+    for (var i = 0; i < pops.length; i++) {
+        pops[i].parentNode.removeChild(pops[i]);
+    }
+}
+
+function backlinksPageURL(prefix, file, id) {
+    return prefix + "srcco-refs.html?file=" + encodeURIComponent(file) + "&def=" + encodeURIComponent(id);
+}
+
+// backlinkHTML is a link to the row with the ref r in it, labeled
+// with the file and line of the ref and the line itself.
+function backlinkHTML(r, prefix) {
+    return '<li><a href="' + pageURL(prefix, r.f + ".html", "row-" + r.r) + '"><span class="backlink-where">' +
+        escapeHTML(r.f + ":" + r.l) + '</span> <code>' + escapeHTML(r.t) + "</code></a></li>";
+}
+
+// setUpBacklinksPage lists all of the refs to a def on
+// srcco-refs.html. The def comes from the "file" and "def" parameters
+// in the URL.
+function setUpBacklinksPage() {
+    var out = document.getElementById("backlinks-results");
+    if (!out) {
+        return;
+    }
+    var file = queryParam("file"), id = queryParam("def");
+    if (!file || !id) {
+        out.textContent = "Which def do you want the references for?";
+        return;
+    }
+    var prefix = document.body.getAttribute("data-resource-prefix") || "";
+    var req = new XMLHttpRequest();
+    req.open("GET", prefix + "srcco-refs/" + file + ".json");
+    req.onload = function() {
+        // id comes from the URL too, so it could be something
+        // like "constructor" that every object has.
+        var index = (req.status === 200 || req.status === 0) && JSON.parse(req.responseText);
+        var def = index && Object.prototype.hasOwnProperty.call(index, id) && index[id];
+        if (!def || !def.refs) {
+            out.textContent = "There aren't any references to " + id + ".";
+            return;
+        }
+        document.title = def.name + " - References";
+        var n = def.refs.length;
+        // file and id come from the URL, so anyone can make a
+        // link that sets them to whatever they like.
+        var html = "<p>" + n + (n === 1 ? " reference" : " references") + ' to <a href="' +
+            pageURL(prefix, file + ".html", id) + '"><b>' + escapeHTML(def.name) + "</b></a> " +
+            escapeHTML(def.kind) + ".</p><ul>";
+        for (var i = 0; i < n; i++) {
+            html += backlinkHTML(def.refs[i], prefix);
+        }
+        out.innerHTML = html + "</ul>";
+    };
+    req.onerror = function() {
+        out.textContent = "Couldn't load the references.";
+    };
+    req.send();
+}
+
+// queryParam returns the value of the parameter name in the URL's
+// query string, or "" if there isn't one.
+function queryParam(name) {
+    var m = new RegExp("[?&]" + name + "=([^&]*)").exec(window.location.search);
+    return m ? decodeURIComponent(m[1].replace(/\+/g, " ")) : "";
+}
+
+// fuzzyMatch checks whether the letters of query appear in name, in
+// order (ignoring case). If they do, it returns a score, which is
+// higher for matches that start a word or are next to each other,
+// and name as HTML with the matching letters in bold.
+function fuzzyMatch(query, name) {
+    var q = query.toLowerCase(), n = name.toLowerCase();
+    var score = 0, qi = 0, last = -2, html = "";
+    for (var i = 0; i < n.length; i++) {
+        var c = escapeHTML(name.charAt(i));
+        if (qi < q.length && n.charAt(i) === q.charAt(qi)) {
+            score += 1;
+            if (i === last + 1) {
+                score += 5;
+            }
+            if (i === 0 || name.charAt(i) !== n.charAt(i) || /[^a-z0-9]/i.test(name.charAt(i - 1))) {
+                score += 3;
+            }
+            last = i;
+            qi++;
+            html += "<b>" + c + "</b>";
+        } else {
+            html += c;
+        }
+    }
+    if (qi < q.length) {
+        return null;
+    }
+    if (n === q) {
+        score += 100;
+    } else if (n.indexOf(q) === 0) {
+        score += 20;
+    }
+    return {score: score, html: html};
+}
+
+// pageURL is the URL of page (a path from the top of the docs), with
+// the fragment hash if there is one, escaped to go in an href. We
+// encode each part of the path, so that a page name like
+// "javascript:..." stays a relative URL.
+function pageURL(prefix, page, hash) {
+    var url = prefix + page.split("/").map(encodeURIComponent).join("/");
+    if (hash) {
+        url += "#" + encodeURI(hash);
+    }
+    return escapeHTML(url);
+}
+
+function escapeHTML(s) {
+    return s.replace(/&/g, "&amp;").replace(/</g, "&lt;").replace(/>/g, "&gt;").replace(/"/g, "&quot;");
+}
@@ -0,0 +1,240 @@
+package srcco
+
+import (
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Comments can have directives in them, which let you arrange the
+// story that a page tells, the way Docco-style tools do:
+//
+//	// srcco:section Title
+//
+// starts a new section, with Title as its heading.
+//
+//	// srcco:hide
+//	...
+//	// srcco:end
+//
+// hides the code in between (you can still open it on the page),
+// which is handy for boring implementation details. And
+//
+//	// srcco:include other.go#Name
+//
+// puts the source of the def Name in other.go (relative to the
+// project) next to the code, so that you can tell the story in the
+// order that makes sense rather than the order that the compiler
+// wants. Without "#Name", it includes all of other.go.
+//
+// A directive takes up a whole line, and it can be in a comment of
+// its own or in a bigger one. We look for them in the source rather
+// than in the docs, because backends like SCIP don't tell us where the
+// comments are, and because the Go backend throws away comment lines
+// that look like directives. But a line that looks like a directive
+// only counts if it's in one of the docs, or if it's a comment all by
+// itself (as far as the highlighter can tell), so that one in a string
+// or in the middle of a block comment stays put.
+
+// directiveRE matches a directive on a line of its own, in the line
+// comment syntax of most languages (or in a one-line block comment).
+var directiveRE = regexp.MustCompile(`(?m)^[ \t]*(?://+|#+|--|;+|/\*+|\*+)[ \t]*srcco:(\w+)[ \t]*(.*?)[ \t]*(?:\*+/)?[ \t]*$`)
+
+// directiveTextRE matches what's left of a directive in a doc's HTML,
+// so that we can take it out.
+var directiveTextRE = regexp.MustCompile(`srcco:\w+[^\n<]*`)
+
+type directive struct {
+	name, arg string
+	// start and end are the offsets of the line the directive is
+	// on, not counting the newline, and at is the offset of its
+	// "srcco:".
+	start, end, at int
+}
+
+// findDirectives returns the directives in src that are in one of the
+// docs in ds or in a comment of their own, in order.
+func findDirectives(src []byte, ds []doc) []directive {
+	var dirs []directive
+	var comments []textRange
+	for _, m := range directiveRE.FindAllSubmatchIndex(src, -1) {
+		d := directive{
+			name:  string(src[m[2]:m[3]]),
+			arg:   string(src[m[4]:m[5]]),
+			start: m[0],
+			end:   m[1],
+			at:    m[2] - len("srcco:"),
+		}
+		if comments == nil {
+			comments, _ = scanRanges(src)
+		}
+		if inDoc(ds, d.at) >= 0 || ownComment(comments, d) {
+			dirs = append(dirs, d)
+		}
+	}
+	return dirs
+}
+
+// inDoc returns the index of the doc in ds that off is in, or -1.
+func inDoc(ds []doc, off int) int {
+	for i, d := range ds {
+		if int(d.Start) <= off && off < int(d.End) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ownComment reports whether d is in one of comments, and the comment
+// is all on d's line, so that d can become a doc without cutting any
+// of the code's tokens in half.
+func ownComment(comments []textRange, d directive) bool {
+	for _, c := range comments {
+		if c.start <= d.at && d.at < c.end {
+			return d.start <= c.start && c.end <= d.end
+		}
+	}
+	return false
+}
+
+// hiddenRange is the code between a "hide" and its "end".
+type hiddenRange struct {
+	start, end int
+}
+
+// applyDirectives works the directives in src into the docs for file.
+// A directive in a doc adds its HTML (a heading, or the source it
+// includes) to the top of the doc; a directive on its own becomes a
+// doc, so that it starts a new row and doesn't show up in the code.
+// It returns the new docs, sorted, along with the ranges of code to
+// hide. includes gets the hash of the source of every file that we
+// include, so that the manifest knows to regenerate the page when it
+// changes.
+func (g *generator) applyDirectives(file string, src []byte, ds []doc, defsMap map[defKey]def, includes map[string]string) ([]doc, []hiddenRange) {
+	dirs := findDirectives(src, ds)
+	if len(dirs) == 0 {
+		return ds, nil
+	}
+	var hidden []hiddenRange
+	hideStart := -1
+	for _, d := range dirs {
+		var h string
+		switch d.name {
+		case "section":
+			h = fmt.Sprintf(`<h2 class="section-title" id="section-%s">%s</h2>`, html.EscapeString(sectionID(d.arg)), html.EscapeString(d.arg))
+			fallthrough
+		case "end":
+			// A section ends the code we're hiding, too.
+			if hideStart >= 0 {
+				hidden = append(hidden, hiddenRange{hideStart, d.start})
+				hideStart = -1
+			}
+		case "hide":
+			if hideStart < 0 {
+				hideStart = d.end
+			}
+		case "include":
+			var err error
+			h, err = g.includeHTML(file, d.arg, defsMap, includes)
+			if err != nil {
+				g.logf("warning: %s: srcco:include %s: %v", file, d.arg, err)
+				h = fmt.Sprintf(`<p class="include-error">Can't include %s: %s</p>`, html.EscapeString(d.arg), html.EscapeString(err.Error()))
+			}
+		default:
+			g.logf("warning: %s: unknown directive srcco:%s", file, d.name)
+			continue
+		}
+		if i := inDoc(ds, d.at); i >= 0 {
+			rest := directiveTextRE.ReplaceAllString(ds[i].Data, "")
+			if strings.TrimSpace(htmlText(rest)) == "" {
+				// The comment was nothing but
+				// directives.
+				rest = ""
+			}
+			ds[i].Data = h + rest
+		} else {
+			ds = append(ds, doc{Format: "text/html", Data: h, Start: uint32(d.start), End: uint32(d.end)})
+		}
+	}
+	if hideStart >= 0 {
+		hidden = append(hidden, hiddenRange{hideStart, len(src)})
+	}
+	sort.Sort(docs(ds))
+	return ds, hidden
+}
+
+// markHidden marks the segments whose code is in one of the hidden
+// ranges.
+func markHidden(segments []segment, hidden []hiddenRange) {
+	for i := range segments {
+		for _, h := range hidden {
+			if segments[i].CodeHTML != "" && h.start <= segments[i].start && segments[i].start < h.end {
+				segments[i].Hidden = true
+			}
+		}
+	}
+}
+
+// sectionID turns a section title into the id of its heading.
+func sectionID(title string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(title), func(r rune) bool { return !isWordRune(r) }), "-")
+}
+
+// includeHTML renders the source that "srcco:include arg" in from
+// refers to.
+func (g *generator) includeHTML(from, arg string, defsMap map[defKey]def, includes map[string]string) (string, error) {
+	file, name, _ := strings.Cut(arg, "#")
+	file = path.Clean(filepath.ToSlash(file))
+	if path.IsAbs(file) || file == ".." || strings.HasPrefix(file, "../") {
+		return "", fmt.Errorf("%s isn't in the project", file)
+	}
+	src, err := ioutil.ReadFile(filepath.Join(g.Dir, filepath.FromSlash(file)))
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("there's no file %s in the project", file)
+	} else if err != nil {
+		return "", err
+	}
+	includes[file] = hashBytes(src)
+	var id string
+	if name != "" {
+		d, ok := findDef(defsMap, file, name)
+		if !ok {
+			return "", fmt.Errorf("there's no def %s in %s", name, file)
+		}
+		if d.DefStart > d.DefEnd || int(d.DefEnd) > len(src) {
+			return "", fmt.Errorf("%s has changed since it was analyzed", file)
+		}
+		src = src[d.DefStart:d.DefEnd]
+		id = d.id()
+	}
+	link := g.defHref(from, file, id)
+	return fmt.Sprintf(`<div class="include"><div class="include-from">From <a href="%s">%s</a></div><pre><code>%s</code></pre></div>`,
+		html.EscapeString(link), html.EscapeString(arg), highlightHTML(src)), nil
+}
+
+// findDef finds the def called name in file. name can be the def's
+// name, or its tree path for defs that belong to others, like
+// "T/Method". If there's more than one, we take the first in the
+// file.
+func findDef(defsMap map[defKey]def, file, name string) (def, bool) {
+	var found def
+	ok := false
+	for _, d := range defsMap {
+		if filepath.ToSlash(d.File) != file {
+			continue
+		}
+		if d.Name != name && strings.TrimPrefix(d.TreePath, "./") != name && d.Path != name {
+			continue
+		}
+		if !ok || d.DefStart < found.DefStart || (d.DefStart == found.DefStart && d.Path < found.Path) {
+			found, ok = d, true
+		}
+	}
+	return found, ok
+}
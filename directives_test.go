@@ -0,0 +1,76 @@
+package srcco
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// testDirectiveSource is a file with directives in all the places that
+// they can look like they are.
+const testDirectiveSource = `package p
+
+// srcco:section Setup
+
+// F does it.
+// srcco:hide
+func F() {}
+
+	// srcco:end
+
+var t = ` + "`" + `
+// srcco:hide
+` + "`" + `
+
+/*
+ srcco:section Not a section
+*/
+func G() {}
+`
+
+func TestFindDirectives(t *testing.T) {
+	src := []byte(testDirectiveSource)
+	docStart := strings.Index(testDirectiveSource, "// F does it.")
+	ds := []doc{{Start: uint32(docStart), End: uint32(strings.Index(testDirectiveSource, "func F"))}}
+	var got []string
+	for _, d := range findDirectives(src, ds) {
+		got = append(got, d.name+" "+d.arg)
+	}
+	// The ones in the string and in the block comment don't
+	// count; the indented one does.
+	want := []string{"section Setup", "hide ", "end "}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got directives %q, want %q", got, want)
+	}
+}
+
+// A directive in a string or in a block comment is just text, so the
+// page keeps it where it is, and doesn't hide anything or try to make
+// a doc out of the middle of the string or comment.
+func TestGenDocsDirectivesInCode(t *testing.T) {
+	opts := Options{Dir: testDir(t, map[string]string{"p.go": testDirectiveSource})}
+	g := testGenerator(t, opts)
+	a := newAnalysis()
+	a.files = []string{"p.go"}
+	var out MemOutput
+	if err := g.genDocs(context.Background(), a, &out, a.files); err != nil {
+		t.Fatal(err)
+	}
+	page := readOutput(t, &out, "p.go.html")
+	if !strings.Contains(page, `id="section-setup"`) {
+		t.Errorf("p.go.html doesn't have the Setup section:\n%s", page)
+	}
+	if strings.Contains(page, "Not a section</h2>") {
+		t.Errorf("p.go.html has the section from the block comment:\n%s", page)
+	}
+	// Only F is hidden: the hide in the string doesn't hide the
+	// rest of the file.
+	if n := strings.Count(page, "code-hidden"); n != 1 {
+		t.Errorf("p.go.html hides %d rows, want 1:\n%s", n, page)
+	}
+	for _, s := range []string{"srcco:hide\n`", "srcco:section Not a section"} {
+		if !strings.Contains(page, s) {
+			t.Errorf("p.go.html lost %s from the code:\n%s", s, page)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package srcco
+
+import (
+	"encoding/json"
+	"html"
+	"html/template"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Besides the symbol search, there's a full-text search over the
+// comments and code on every page. We can't run grep in a browser, so
+// at build time we break the text of each row into lines, and index
+// the lines by the words in them. srcco-search.html (see srcco.js)
+// looks up the words you searched for and shows you the lines that
+// have all of them, with links to the rows they're in.
+//
+// The pages already have the text in them, so the index doesn't: it
+// only says where each word is, and srcco.js fetches the pages with
+// results on them to show the lines. That keeps the index small
+// enough to load for every search, even for a big project.
+
+// textIndexName is the name of the full-text index in the output
+// directory.
+const textIndexName = "srcco-search.json"
+
+// textIndexVersion changes whenever the format of the full-text index
+// changes, so that srcco.js can tell if it's reading an old one.
+const textIndexVersion = 2
+
+// A textLine is a line of text on a page. Row is the index of the
+// segment it's in, and Doc tells us whether it came from the doc or
+// the code. The manifest keeps the lines for each page, so we don't
+// have to regenerate pages that haven't changed to index them.
+type textLine struct {
+	Row  int    `json:"r"`
+	Doc  bool   `json:"d,omitempty"`
+	Text string `json:"t"`
+}
+
+// textIndex is what we write to textIndexName. Words has every word
+// (in lower case), sorted, so that srcco.js can find the words that
+// start with what you typed without looking at all of them, and
+// Postings[i] has the lines that Words[i] is in, in order. Each line
+// is three numbers: the page (an index into Pages), the row, and which
+// of the row's lines it is, counting the doc's lines first and leaving
+// out the blank ones, as segmentLines does.
+type textIndex struct {
+	Version  int      `json:"version"`
+	Pages    []string `json:"pages"`
+	Words    []string `json:"words"`
+	Postings [][]int  `json:"postings"`
+}
+
+// segmentLines breaks the text of segments up into lines, leaving out
+// the blank ones.
+func segmentLines(segments []segment) []textLine {
+	var lines []textLine
+	add := func(row int, doc bool, h string) {
+		for _, l := range strings.Split(htmlText(h), "\n") {
+			l = strings.TrimRightFunc(l, unicode.IsSpace)
+			if doc {
+				l = strings.TrimLeftFunc(l, unicode.IsSpace)
+			}
+			if l != "" {
+				lines = append(lines, textLine{Row: row, Doc: doc, Text: l})
+			}
+		}
+	}
+	for i, s := range segments {
+		add(i, true, string(s.DocHTML))
+		add(i, false, string(s.CodeHTML))
+	}
+	return lines
+}
+
+// htmlText turns HTML into plain text by throwing away the tags and
+// unescaping what's left. That's all we need for the HTML in
+// segments, which we (or godoc) wrote ourselves.
+func htmlText(h string) string {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(h, '<')
+		if i < 0 {
+			b.WriteString(h)
+			break
+		}
+		b.WriteString(h[:i])
+		j := strings.IndexByte(h[i:], '>')
+		if j < 0 {
+			break
+		}
+		h = h[i+j+1:]
+	}
+	return html.UnescapeString(b.String())
+}
+
+// textWords splits s into the words that we index: runs of letters,
+// digits and underscores, in lower case. Single letters aren't worth
+// indexing.
+func textWords(s string) []string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return !isWordRune(r) })
+	ws := words[:0]
+	for _, w := range words {
+		if len([]rune(w)) > 1 {
+			ws = append(ws, w)
+		}
+	}
+	return ws
+}
+
+// writeTextIndex writes the full-text index for the pages for files
+// to out. lines[i] holds the lines of the page for files[i].
+func writeTextIndex(out Output, files []string, lines [][]textLine) error {
+	idx := textIndex{Version: textIndexVersion}
+	postings := map[string][]int{}
+	for i, f := range files {
+		if len(lines[i]) == 0 {
+			continue
+		}
+		page := len(idx.Pages)
+		idx.Pages = append(idx.Pages, htmlFilename(f))
+		row, n := -1, 0
+		for _, l := range lines[i] {
+			if l.Row != row {
+				row, n = l.Row, 0
+			}
+			for _, w := range textWords(l.Text) {
+				// A word can be in a line more than
+				// once, but we only need the line once.
+				ps := postings[w]
+				if k := len(ps); k != 0 && ps[k-3] == page && ps[k-2] == row && ps[k-1] == n {
+					continue
+				}
+				postings[w] = append(ps, page, row, n)
+			}
+			n++
+		}
+	}
+	for w := range postings {
+		idx.Words = append(idx.Words, w)
+	}
+	// The words are sorted, so the index only changes when the
+	// text does.
+	sort.Strings(idx.Words)
+	for _, w := range idx.Words {
+		idx.Postings = append(idx.Postings, postings[w])
+	}
+	b, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	return out.WriteFile(textIndexName, b)
+}
+
+// searchPageName is the page that shows the results of a full-text
+// search.
+const searchPageName = "srcco-search.html"
+
+// writeSearchPage writes the page for full-text search results to
+// out, using the template t. It has the same table of contents as the
+// code pages, and srcco.js fills in the results.
+func writeSearchPage(out Output, t *template.Template, fileTOC string) error {
+	var b strings.Builder
+	data := HTMLOutput{Title: "Search", FileTableOfContents: template.HTML(fileTOC)}
+	if err := t.Execute(&b, data); err != nil {
+		return err
+	}
+	return out.WriteFile(searchPageName, []byte(b.String()))
+}
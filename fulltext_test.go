@@ -0,0 +1,52 @@
+package srcco
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestWriteTextIndex(t *testing.T) {
+	lines := [][]textLine{
+		{
+			{Row: 0, Doc: true, Text: "Package greet says hello."},
+			{Row: 0, Text: "package greet"},
+			{Row: 2, Text: "func Hello() { hello(hello) }"},
+		},
+		// A page with no text isn't in the index at all.
+		nil,
+		{
+			{Row: 1, Text: "greet.Hello()"},
+		},
+	}
+	var out MemOutput
+	if err := writeTextIndex(&out, []string{"greet/greet.go", "empty.go", "main.go"}, lines); err != nil {
+		t.Fatal(err)
+	}
+	var idx textIndex
+	if err := json.Unmarshal([]byte(readOutput(t, &out, textIndexName)), &idx); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"greet/greet.go.html", "main.go.html"}; !reflect.DeepEqual(idx.Pages, want) {
+		t.Errorf("got pages %v, want %v", idx.Pages, want)
+	}
+	if !sort.StringsAreSorted(idx.Words) {
+		t.Errorf("the words aren't sorted: %v", idx.Words)
+	}
+	postings := map[string][]int{}
+	for i, w := range idx.Words {
+		postings[w] = idx.Postings[i]
+	}
+	// The lines are numbered from the start of each row, and a
+	// line is only in a word's postings once.
+	for w, want := range map[string][]int{
+		"greet": {0, 0, 0, 0, 0, 1, 1, 1, 0},
+		"hello": {0, 0, 0, 0, 2, 0, 1, 1, 0},
+		"func":  {0, 2, 0},
+	} {
+		if !reflect.DeepEqual(postings[w], want) {
+			t.Errorf("got postings %v for %q, want %v", postings[w], w, want)
+		}
+	}
+}
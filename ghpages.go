@@ -0,0 +1,312 @@
+package srcco
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// We publish the docs to GitHub Pages by committing them to the
+// repository's gh-pages branch. We used to do that with a bash script
+// (borrowed from Groc) that checked out the branch, copied the docs
+// over it and committed them, but we don't need a work tree at all:
+// we write the docs straight into the object store as blobs and
+// trees, point gh-pages at a new commit with that tree, and push it.
+// That way, your checkout isn't touched, and it works anywhere that
+// Go does.
+
+// ghPagesBranch is the branch that GitHub serves Pages from.
+const ghPagesBranch = plumbing.ReferenceName("refs/heads/gh-pages")
+
+// publishGitHubPages commits the docs in sitePath to the gh-pages
+// branch of the git repository that Dir is in, on top of whatever is
+// already there, and pushes the branch to Remote if Push is set. If DryRun is
+// set, it works out what it would commit but doesn't write anything
+// to the repository.
+func (g *generator) publishGitHubPages(ctx context.Context, sitePath string) error {
+	dir := g.Dir
+	// Dir can be anywhere in the repository, not just at the top.
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return fmt.Errorf("opening git repository in %s: %v", dir, err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("finding HEAD: %v", err)
+	}
+
+	// In a dry run, the new objects go into memory and disappear
+	// when we're done.
+	var s storer.EncodedObjectStorer = repo.Storer
+	if g.DryRun {
+		s = memory.NewStorage()
+	}
+
+	// We keep the project's .gitignore in gh-pages, so that
+	// anyone who checks the branch out doesn't accidentally
+	// commit their hidden files to it. It goes straight into the
+	// tree, rather than into sitePath, so that a dry run doesn't
+	// write anything at all.
+	var extra []object.TreeEntry
+	if f, err := os.Open(filepath.Join(dir, ".gitignore")); err == nil {
+		h, err := storeBlob(s, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		extra = append(extra, object.TreeEntry{Name: ".gitignore", Mode: filemode.Regular, Hash: h})
+	}
+	tree, err := writeTree(s, sitePath, true, extra...)
+	if err != nil {
+		return err
+	}
+
+	parent, err := g.ghPagesParent(ctx, repo)
+	if err != nil {
+		return err
+	}
+	var parents []plumbing.Hash
+	if parent != nil {
+		if parent.TreeHash == tree {
+			g.logf("The docs in gh-pages are already up to date.")
+			return nil
+		}
+		parents = []plumbing.Hash{parent.Hash}
+	} else {
+		g.logf("No gh-pages branch exists. Creating one.")
+	}
+
+	sig, err := gitSignature(repo)
+	if err != nil {
+		return err
+	}
+	c := &object.Commit{
+		Author:       sig,
+		Committer:    sig,
+		Message:      fmt.Sprintf("Generated documentation for %s\n", head.Hash()),
+		TreeHash:     tree,
+		ParentHashes: parents,
+	}
+	commit, err := storeObject(s, c)
+	if err != nil {
+		return err
+	}
+	if g.DryRun {
+		if g.Push {
+			g.logf("Would commit the docs to gh-pages as %s and push them to %s.", commit, g.Remote)
+		} else {
+			g.logf("Would commit the docs to gh-pages as %s.", commit)
+		}
+		return nil
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(ghPagesBranch, commit)); err != nil {
+		return err
+	}
+	g.logf("Committed the docs to gh-pages as %s.", commit)
+	if !g.Push {
+		return nil
+	}
+
+	g.vLogf("Pushing gh-pages to %s", g.Remote)
+	err = repo.PushContext(ctx, &git.PushOptions{
+		RemoteName: g.Remote,
+		RefSpecs:   []config.RefSpec{config.RefSpec(ghPagesBranch + ":" + ghPagesBranch)},
+		Progress:   g.progress(),
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("pushing gh-pages to %s: %v", g.Remote, err)
+	}
+	return nil
+}
+
+// ghPagesParent finds the commit that the new docs go on top of: the
+// local gh-pages branch if there is one, and otherwise the remote's.
+// It returns nil if neither of them exists yet.
+func (g *generator) ghPagesParent(ctx context.Context, repo *git.Repository) (*object.Commit, error) {
+	ref, err := repo.Reference(ghPagesBranch, true)
+	if err == plumbing.ErrReferenceNotFound {
+		ref, err = g.remoteGHPages(ctx, repo)
+	}
+	if err == plumbing.ErrReferenceNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return repo.CommitObject(ref.Hash())
+}
+
+// remoteGHPages fetches Remote's gh-pages branch, in case someone
+// has published from another clone, and returns it.
+func (g *generator) remoteGHPages(ctx context.Context, repo *git.Repository) (*plumbing.Reference, error) {
+	remoteRef := plumbing.NewRemoteReferenceName(g.Remote, ghPagesBranch.Short())
+	if g.DryRun {
+		// Fetching would update the remote-tracking branch,
+		// so we settle for whatever we fetched last time.
+		g.vLogf("Not fetching gh-pages from %s in a dry run", g.Remote)
+	} else {
+		g.vLogf("Fetching gh-pages from %s", g.Remote)
+		err := repo.FetchContext(ctx, &git.FetchOptions{
+			RemoteName: g.Remote,
+			RefSpecs:   []config.RefSpec{config.RefSpec("+" + ghPagesBranch + ":" + remoteRef)},
+			Progress:   g.progress(),
+		})
+		switch err.(type) {
+		case nil:
+		case git.NoMatchingRefSpecError:
+			// The remote doesn't have a gh-pages branch
+			// either.
+			return nil, plumbing.ErrReferenceNotFound
+		default:
+			if err == transport.ErrEmptyRemoteRepository {
+				// Nothing has been pushed to the remote
+				// at all yet.
+				return nil, plumbing.ErrReferenceNotFound
+			}
+			if err != git.NoErrAlreadyUpToDate && err != git.ErrRemoteNotFound {
+				return nil, fmt.Errorf("fetching gh-pages from %s: %v", g.Remote, err)
+			}
+		}
+	}
+	return repo.Reference(remoteRef, true)
+}
+
+// writeTree stores the files in dir, and the entries in extra, as a
+// tree, and returns its hash. An entry in extra takes the place of a
+// file in dir with the same name. The manifest only matters to the
+// next run of srcco, so we leave it out of the top-level tree.
+func writeTree(s storer.EncodedObjectStorer, dir string, top bool, extra ...object.TreeEntry) (plumbing.Hash, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	t := &object.Tree{Entries: append([]object.TreeEntry(nil), extra...)}
+	replaced := map[string]bool{}
+	for _, e := range extra {
+		replaced[e.Name] = true
+	}
+	for _, fi := range fis {
+		name := fi.Name()
+		if (top && name == manifestName) || replaced[name] {
+			continue
+		}
+		p := filepath.Join(dir, name)
+		switch {
+		case fi.IsDir():
+			h, err := writeTree(s, p, false)
+			if err != nil {
+				return plumbing.ZeroHash, err
+			}
+			// Git doesn't have empty directories.
+			if h == emptyTree {
+				continue
+			}
+			t.Entries = append(t.Entries, object.TreeEntry{Name: name, Mode: filemode.Dir, Hash: h})
+		case fi.Mode().IsRegular():
+			h, err := writeBlob(s, p)
+			if err != nil {
+				return plumbing.ZeroHash, err
+			}
+			mode := filemode.Regular
+			if fi.Mode()&0111 != 0 {
+				mode = filemode.Executable
+			}
+			t.Entries = append(t.Entries, object.TreeEntry{Name: name, Mode: mode, Hash: h})
+		}
+	}
+	// Git sorts tree entries as if directories had a trailing
+	// slash.
+	sortName := func(e object.TreeEntry) string {
+		if e.Mode == filemode.Dir {
+			return e.Name + "/"
+		}
+		return e.Name
+	}
+	sort.Slice(t.Entries, func(i, j int) bool { return sortName(t.Entries[i]) < sortName(t.Entries[j]) })
+	return storeObject(s, t)
+}
+
+// emptyTree is the hash of the tree with nothing in it.
+var emptyTree = plumbing.ComputeHash(plumbing.TreeObject, nil)
+
+func writeBlob(s storer.EncodedObjectStorer, file string) (plumbing.Hash, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	defer f.Close()
+	return storeBlob(s, f)
+}
+
+// storeBlob stores what's in r as a blob in s.
+func storeBlob(s storer.EncodedObjectStorer, r io.Reader) (plumbing.Hash, error) {
+	o := s.NewEncodedObject()
+	o.SetType(plumbing.BlobObject)
+	w, err := o.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if err := w.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return s.SetEncodedObject(o)
+}
+
+// storeObject encodes a tree or commit into s.
+func storeObject(s storer.EncodedObjectStorer, v interface {
+	Encode(plumbing.EncodedObject) error
+}) (plumbing.Hash, error) {
+	o := s.NewEncodedObject()
+	if err := v.Encode(o); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return s.SetEncodedObject(o)
+}
+
+// gitSignature signs the commit as the user, according to their git
+// config (the repository's, then the global one), or $EMAIL, which
+// git falls back on too. GitHub won't link a commit without an email
+// to anyone, so if we can't find one, we ask for it rather than make
+// one up.
+func gitSignature(repo *git.Repository) (object.Signature, error) {
+	cfg, err := repo.ConfigScoped(config.GlobalScope)
+	if err != nil {
+		return object.Signature{}, err
+	}
+	sig := object.Signature{Name: cfg.User.Name, Email: cfg.User.Email, When: time.Now()}
+	if sig.Name == "" {
+		sig.Name = "srcco"
+	}
+	if sig.Email == "" {
+		sig.Email = os.Getenv("EMAIL")
+	}
+	if sig.Email == "" {
+		return object.Signature{}, fmt.Errorf("no email to commit gh-pages as; set it with 'git config user.email'")
+	}
+	return sig, nil
+}
+
+// progress is where git's progress messages go: the log if we're
+// being verbose, and nowhere otherwise.
+func (g *generator) progress() io.Writer {
+	if g.Verbose && g.Log != nil {
+		return g.Log
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package srcco
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// testGitRepo makes a git repository with one commit in it, and a bare
+// repository for it to push to as origin. It returns the repository,
+// the remote, and the repository's directory.
+func testGitRepo(t *testing.T) (repo, remote *git.Repository, dir string) {
+	t.Helper()
+	remoteDir := t.TempDir()
+	remote, err := git.PlainInit(remoteDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, dir = testGitClone(t, remoteDir)
+	return repo, remote, dir
+}
+
+// testGitClone makes a git repository with one commit in it, whose
+// origin is the repository at remoteDir, and returns it and its
+// directory.
+func testGitClone(t *testing.T, remoteDir string) (*git.Repository, string) {
+	t.Helper()
+	dir := testDir(t, map[string]string{
+		"main.go":    testSources["main.go"],
+		".gitignore": "*.swp\n",
+	})
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// gh-pages is committed as whoever the config says, so that
+	// the tests don't depend on the global config.
+	cfg, err := repo.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.User.Name, cfg.User.Email = "Test", "test@example.com"
+	if err := repo.SetConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Add("main.go"); err != nil {
+		t.Fatal(err)
+	}
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()}
+	if _, err := w.Commit("Add main.go\n", &git.CommitOptions{Author: sig}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remoteDir}}); err != nil {
+		t.Fatal(err)
+	}
+	return repo, dir
+}
+
+// testSite writes a site with the page in it to a temporary
+// directory, like genDocs would, and returns it.
+func testSite(t *testing.T, page string) string {
+	t.Helper()
+	return testDir(t, map[string]string{
+		"main.go.html": page,
+		manifestName:   "{}",
+	})
+}
+
+// ghPagesCommit returns the commit that gh-pages points at in repo.
+func ghPagesCommit(t *testing.T, repo *git.Repository) *object.Commit {
+	t.Helper()
+	ref, err := repo.Reference(ghPagesBranch, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// countObjects returns the number of objects in repo.
+func countObjects(t *testing.T, repo *git.Repository) int {
+	t.Helper()
+	iter, err := repo.Storer.IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	iter.ForEach(func(plumbing.EncodedObject) error {
+		n++
+		return nil
+	})
+	return n
+}
+
+func TestPublishGitHubPages(t *testing.T) {
+	repo, remote, dir := testGitRepo(t)
+	g := testGenerator(t, Options{Dir: dir, Push: true})
+	ctx := context.Background()
+
+	// There's no gh-pages branch anywhere, so the first commit is
+	// the root of a new one.
+	site := testSite(t, "<p>one</p>")
+	if err := g.publishGitHubPages(ctx, site); err != nil {
+		t.Fatal(err)
+	}
+	first := ghPagesCommit(t, repo)
+	if len(first.ParentHashes) != 0 {
+		t.Errorf("the first gh-pages commit has parents %v, want none", first.ParentHashes)
+	}
+	if got := ghPagesCommit(t, remote); got.Hash != first.Hash {
+		t.Errorf("origin's gh-pages is %s, want %s", got.Hash, first.Hash)
+	}
+	tree, err := first.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tree.File("main.go.html"); err != nil {
+		t.Errorf("main.go.html isn't in gh-pages: %v", err)
+	}
+	if f, err := tree.File(".gitignore"); err != nil {
+		t.Errorf(".gitignore isn't in gh-pages: %v", err)
+	} else if s, _ := f.Contents(); s != "*.swp\n" {
+		t.Errorf("the .gitignore in gh-pages is %q, want the project's", s)
+	}
+	if _, err := tree.File(manifestName); err == nil {
+		t.Errorf("%s is in gh-pages", manifestName)
+	}
+
+	// Publishing the same docs again doesn't make a new commit.
+	if err := g.publishGitHubPages(ctx, site); err != nil {
+		t.Fatal(err)
+	}
+	if got := ghPagesCommit(t, repo); got.Hash != first.Hash {
+		t.Errorf("republishing the same docs committed %s", got.Hash)
+	}
+
+	// New docs go on top of the old ones.
+	if err := ioutil.WriteFile(filepath.Join(site, "main.go.html"), []byte("<p>two</p>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.publishGitHubPages(ctx, site); err != nil {
+		t.Fatal(err)
+	}
+	second := ghPagesCommit(t, repo)
+	if second.Hash == first.Hash {
+		t.Fatal("publishing new docs didn't make a new commit")
+	}
+	if len(second.ParentHashes) != 1 || second.ParentHashes[0] != first.Hash {
+		t.Errorf("the second gh-pages commit has parents %v, want [%s]", second.ParentHashes, first.Hash)
+	}
+	if got := ghPagesCommit(t, remote); got.Hash != second.Hash {
+		t.Errorf("origin's gh-pages is %s, want %s", got.Hash, second.Hash)
+	}
+}
+
+func TestPublishGitHubPagesDryRun(t *testing.T) {
+	repo, remote, dir := testGitRepo(t)
+	ctx := context.Background()
+	site := testSite(t, "<p>one</p>")
+	if err := testGenerator(t, Options{Dir: dir}).publishGitHubPages(ctx, site); err != nil {
+		t.Fatal(err)
+	}
+	before := ghPagesCommit(t, repo).Hash
+	objects := countObjects(t, repo)
+
+	if err := ioutil.WriteFile(filepath.Join(site, "main.go.html"), []byte("<p>two</p>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	g := testGenerator(t, Options{Dir: dir, Push: true, DryRun: true})
+	if err := g.publishGitHubPages(ctx, site); err != nil {
+		t.Fatal(err)
+	}
+	if got := ghPagesCommit(t, repo).Hash; got != before {
+		t.Errorf("a dry run moved gh-pages from %s to %s", before, got)
+	}
+	if got := countObjects(t, repo); got != objects {
+		t.Errorf("a dry run stored %d objects", got-objects)
+	}
+	if _, err := remote.Reference(ghPagesBranch, true); err != plumbing.ErrReferenceNotFound {
+		t.Errorf("a dry run pushed gh-pages to origin (err = %v)", err)
+	}
+	if _, err := os.Stat(filepath.Join(site, ".gitignore")); !os.IsNotExist(err) {
+		t.Errorf("a dry run wrote .gitignore to the site (err = %v)", err)
+	}
+}
+
+func TestPublishGitHubPagesFromRemote(t *testing.T) {
+	repo, remote, dir := testGitRepo(t)
+	ctx := context.Background()
+	if err := testGenerator(t, Options{Dir: dir, Push: true}).publishGitHubPages(ctx, testSite(t, "<p>one</p>")); err != nil {
+		t.Fatal(err)
+	}
+	first := ghPagesCommit(t, repo)
+
+	// Another clone, which has never had a gh-pages branch, puts
+	// its docs on top of the ones it fetches from origin. Its
+	// project is in a subdirectory of the repository.
+	origin, err := repo.Remote("origin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone, cloneDir := testGitClone(t, origin.Config().URLs[0])
+	sub := filepath.Join(cloneDir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := testGenerator(t, Options{Dir: sub, Push: true}).publishGitHubPages(ctx, testSite(t, "<p>two</p>")); err != nil {
+		t.Fatal(err)
+	}
+	second := ghPagesCommit(t, clone)
+	if len(second.ParentHashes) != 1 || second.ParentHashes[0] != first.Hash {
+		t.Errorf("the clone's gh-pages commit has parents %v, want [%s]", second.ParentHashes, first.Hash)
+	}
+	if got := ghPagesCommit(t, remote); got.Hash != second.Hash {
+		t.Errorf("origin's gh-pages is %s, want %s", got.Hash, second.Hash)
+	}
+}
@@ -0,0 +1,406 @@
+package srcco
+
+import (
+	"bytes"
+	"context"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// goUnitType is the unit type srclib's Go toolchain uses for
+// packages. We use it too so that links to Sourcegraph.com keep
+// working.
+const goUnitType = "GoPackage"
+
+// loadGoProject analyzes a Go project in-process with go/packages and
+// go/types. It loads every package under dir and collects the defs,
+// refs and docs for each of its files, which gives us the same
+// results that "src api units" and "src api list" would, but without
+// needing srclib or its Go toolchain to be installed.
+func (g *generator) loadGoProject(ctx context.Context, dir string) (*analysis, error) {
+	fset := token.NewFileSet()
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax |
+			packages.NeedImports | packages.NeedTypes | packages.NeedTypesInfo,
+		Context: ctx,
+		Dir:     dir,
+		Fset:    fset,
+	}
+	g.vLogf("Loading Go packages in %s", dir)
+	pkgs, err := packages.Load(cfg, "./...")
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].PkgPath < pkgs[j].PkgPath })
+
+	p := newAnalysis()
+	// We analyze the source right before we use it, so it can
+	// only be stale if someone edits it in between.
+	p.hint = "It changed while srcco was running: run srcco again"
+	// keys maps every object we create a def for to that def's
+	// key, so that we can resolve uses of those objects to defs
+	// later on. We need it for fields and interface methods,
+	// because go/types doesn't tell us which type they belong to.
+	keys := map[types.Object]defKey{}
+	type goFile struct {
+		name string
+		ast  *ast.File
+		pkg  *packages.Package
+	}
+	var files []goFile
+	for _, pkg := range pkgs {
+		// Packages with errors still have (partial) type
+		// information, so we report the errors and keep going
+		// instead of giving up on the whole project.
+		for _, err := range pkg.Errors {
+			g.vLogf("Go package %s: %v", pkg.PkgPath, err)
+		}
+		var pkgFiles []string
+		for _, f := range pkg.Syntax {
+			name, err := filepath.Rel(dir, fset.File(f.Pos()).Name())
+			if err != nil || strings.HasPrefix(name, "..") {
+				// This is a generated file that lives
+				// outside the project (cgo does this).
+				continue
+			}
+			name = filepath.ToSlash(name)
+			pkgFiles = append(pkgFiles, name)
+			files = append(files, goFile{name, f, pkg})
+			p.defs[name] = goDefs(fset, pkg, f, name, keys)
+		}
+		sort.Strings(pkgFiles)
+		p.files = append(p.files, pkgFiles...)
+	}
+	// We can only resolve refs after we have seen every def in
+	// the project, because a file may refer to a def in a file
+	// that we haven't walked yet.
+	for _, f := range files {
+		src, err := ioutil.ReadFile(filepath.Join(dir, f.name))
+		if err != nil {
+			return nil, err
+		}
+		p.refs[f.name] = goRefs(fset, f.pkg, f.ast, f.name, keys)
+		p.docs[f.name] = goDocs(fset, f.ast, src)
+	}
+	return p, nil
+}
+
+// goDefs walks the top-level declarations of f and returns the defs
+// in it. Like srclib's Go toolchain, a def's range covers its whole
+// declaration, so the anchor that ann creates at DefStart lands on
+// the "func" or "type" keyword.
+func goDefs(fset *token.FileSet, pkg *packages.Package, f *ast.File, filename string, keys map[types.Object]defKey) []def {
+	var ds []def
+	add := func(ident *ast.Ident, path, kind string, start, end token.Pos, doc *ast.CommentGroup) {
+		if ident.Name == "_" {
+			return
+		}
+		d := def{
+			defKey:   defKey{Unit: pkg.PkgPath, Path: path},
+			Name:     ident.Name,
+			Kind:     kind,
+			File:     filename,
+			DefStart: uint32(fset.Position(start).Offset),
+			DefEnd:   uint32(fset.Position(end).Offset),
+			TreePath: "./" + path,
+			Doc:      goDocSummary(doc),
+		}
+		if obj := pkg.TypesInfo.Defs[ident]; obj != nil {
+			keys[obj] = d.defKey
+			d.Signature = goSignature(obj)
+		}
+		ds = append(ds, d)
+	}
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			// There can be any number of init functions in a
+			// package, so they can't have a unique path.
+			if decl.Recv == nil && decl.Name.Name == "init" {
+				continue
+			}
+			if decl.Recv != nil && len(decl.Recv.List) != 0 {
+				recv := recvTypeName(decl.Recv.List[0].Type)
+				add(decl.Name, recv+"/"+decl.Name.Name, "method", decl.Pos(), decl.End(), decl.Doc)
+			} else {
+				add(decl.Name, decl.Name.Name, "func", decl.Pos(), decl.End(), decl.Doc)
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				// Specs that aren't in a parenthesized group
+				// start at the keyword, like funcs do.
+				// Their comments go on the keyword too.
+				start := spec.Pos()
+				doc := decl.Doc
+				if decl.Lparen.IsValid() {
+					doc = nil
+				} else {
+					start = decl.Pos()
+				}
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if spec.Doc != nil {
+						doc = spec.Doc
+					}
+					add(spec.Name, spec.Name.Name, "type", start, spec.End(), doc)
+					goMemberDefs(spec, add)
+				case *ast.ValueSpec:
+					if spec.Doc != nil {
+						doc = spec.Doc
+					}
+					kind := "var"
+					if decl.Tok == token.CONST {
+						kind = "const"
+					}
+					for _, name := range spec.Names {
+						add(name, name.Name, kind, start, spec.End(), doc)
+					}
+				}
+			}
+		}
+	}
+	return ds
+}
+
+// goMemberDefs adds the fields of a struct type and the methods of an
+// interface type as children of that type.
+func goMemberDefs(spec *ast.TypeSpec, add func(*ast.Ident, string, string, token.Pos, token.Pos, *ast.CommentGroup)) {
+	var (
+		fields *ast.FieldList
+		kind   string
+	)
+	switch t := spec.Type.(type) {
+	case *ast.StructType:
+		fields, kind = t.Fields, "field"
+	case *ast.InterfaceType:
+		fields, kind = t.Methods, "method"
+	default:
+		return
+	}
+	for _, field := range fields.List {
+		names := field.Names
+		if len(names) == 0 {
+			// Embedded fields are named after their type.
+			// Embedded interfaces aren't defs at all.
+			if kind == "method" {
+				continue
+			}
+			if ident := embeddedIdent(field.Type); ident != nil {
+				names = []*ast.Ident{ident}
+			}
+		}
+		// Members often have their comment at the end of
+		// the line instead of above them.
+		doc := field.Doc
+		if doc == nil {
+			doc = field.Comment
+		}
+		for _, name := range names {
+			add(name, spec.Name.Name+"/"+name.Name, kind, field.Pos(), field.End(), doc)
+		}
+	}
+}
+
+// goSignature returns the declaration of obj for its hover card, like
+// "func (g *generator) ann(src []byte) error". Names from obj's own
+// package aren't qualified. The underlying types of structs and
+// interfaces can go on for pages, so we leave them out.
+func goSignature(obj types.Object) string {
+	qual := func(p *types.Package) string {
+		if p == obj.Pkg() {
+			return ""
+		}
+		return p.Name()
+	}
+	if tn, ok := obj.(*types.TypeName); ok && !tn.IsAlias() {
+		s := "type " + tn.Name()
+		if named, ok := tn.Type().(*types.Named); ok && named.TypeParams().Len() != 0 {
+			var params []string
+			for i := 0; i < named.TypeParams().Len(); i++ {
+				tp := named.TypeParams().At(i)
+				params = append(params, tp.Obj().Name()+" "+types.TypeString(tp.Constraint(), qual))
+			}
+			s += "[" + strings.Join(params, ", ") + "]"
+		}
+		switch u := tn.Type().Underlying().(type) {
+		case *types.Struct:
+			return s + " struct{...}"
+		case *types.Interface:
+			return s + " interface{...}"
+		default:
+			return s + " " + types.TypeString(u, qual)
+		}
+	}
+	return types.ObjectString(obj, qual)
+}
+
+// goDocSummary returns the first paragraph of a doc comment as one
+// line of plain text.
+func goDocSummary(cg *ast.CommentGroup) string {
+	if cg == nil {
+		return ""
+	}
+	para, _, _ := strings.Cut(strings.TrimSpace(cg.Text()), "\n\n")
+	return strings.Join(strings.Fields(para), " ")
+}
+
+// recvTypeName returns the name of the type in a method receiver,
+// e.g. "T" for "*T" or "T[K, V]".
+func recvTypeName(expr ast.Expr) string {
+	if ident := embeddedIdent(expr); ident != nil {
+		return ident.Name
+	}
+	return ""
+}
+
+// embeddedIdent digs the type name out of an embedded field or method
+// receiver type expression.
+func embeddedIdent(expr ast.Expr) *ast.Ident {
+	for {
+		switch e := expr.(type) {
+		case *ast.Ident:
+			return e
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.SelectorExpr:
+			expr = e.Sel
+		default:
+			return nil
+		}
+	}
+}
+
+// goRefs returns a ref for every identifier in f that refers to a
+// def. Just like with srclib, every def is also a ref to itself.
+func goRefs(fset *token.FileSet, pkg *packages.Package, f *ast.File, filename string, keys map[types.Object]defKey) []ref {
+	var rs []ref
+	ast.Inspect(f, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		obj := pkg.TypesInfo.ObjectOf(ident)
+		if obj == nil {
+			return true
+		}
+		r, ok := goRef(obj, keys)
+		if !ok {
+			return true
+		}
+		r.File = filename
+		r.Start = uint32(fset.Position(ident.Pos()).Offset)
+		rs = append(rs, r)
+		return true
+	})
+	sort.Sort(refs(rs))
+	return rs
+}
+
+// goRef creates a ref (without a position) to the def for obj. Defs
+// in the project are looked up in keys; for everything else we guess
+// the path the same way srclib's Go toolchain would, so that
+// -enable-sourcegraph can link to it.
+func goRef(obj types.Object, keys map[types.Object]defKey) (ref, bool) {
+	if f, ok := obj.(*types.Func); ok {
+		obj = f.Origin()
+	} else if v, ok := obj.(*types.Var); ok {
+		obj = v.Origin()
+	}
+	if k, ok := keys[obj]; ok {
+		return ref{DefUnitType: goUnitType, DefUnit: k.Unit, DefPath: k.Path}, true
+	}
+	if obj.Pkg() == nil {
+		// Builtins like "len" and "error" don't have defs.
+		return ref{}, false
+	}
+	var path string
+	switch {
+	case obj.Parent() == obj.Pkg().Scope():
+		path = obj.Name()
+	case isMethod(obj):
+		recv := obj.(*types.Func).Type().(*types.Signature).Recv().Type()
+		if p, ok := recv.(*types.Pointer); ok {
+			recv = p.Elem()
+		}
+		named, ok := recv.(*types.Named)
+		if !ok {
+			return ref{}, false
+		}
+		path = named.Obj().Name() + "/" + obj.Name()
+	default:
+		// Locals, and fields of types outside the project.
+		return ref{}, false
+	}
+	return ref{
+		DefRepo:     goRepo(obj.Pkg().Path()),
+		DefUnitType: goUnitType,
+		DefUnit:     obj.Pkg().Path(),
+		DefPath:     path,
+	}, true
+}
+
+func isMethod(obj types.Object) bool {
+	f, ok := obj.(*types.Func)
+	return ok && f.Type().(*types.Signature).Recv() != nil
+}
+
+// goRepo guesses the repository that holds the package importPath.
+// The standard library doesn't live in a repository we can link to,
+// so it gets an empty string.
+func goRepo(importPath string) string {
+	parts := strings.Split(importPath, "/")
+	if !strings.Contains(parts[0], ".") {
+		return ""
+	}
+	switch parts[0] {
+	case "github.com", "bitbucket.org", "gitlab.com", "sourcegraph.com":
+		if len(parts) >= 3 {
+			return strings.Join(parts[:3], "/")
+		}
+	}
+	return importPath
+}
+
+// goDocs turns the comments in f into docs. We only take comments
+// that begin a line, because a comment at the end of a line of code
+// belongs with that code and not in the doc column. The docs are the
+// plain text of the comments, which renderPage renders as Markdown,
+// like the comments from every other backend.
+func goDocs(fset *token.FileSet, f *ast.File, src []byte) []doc {
+	tf := fset.File(f.Pos())
+	var ds []doc
+	for _, cg := range f.Comments {
+		start := tf.Offset(cg.Pos())
+		lineStart := tf.Offset(tf.LineStart(tf.Line(cg.Pos())))
+		if len(bytes.TrimLeft(src[lineStart:start], " \t")) != 0 {
+			continue
+		}
+		// Directives like "//go:build" are dropped by
+		// Text(), so a group of nothing but directives
+		// stays in the code.
+		text := cg.Text()
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		ds = append(ds, doc{
+			Format: "text/plain",
+			Data:   text,
+			Start:  uint32(start),
+			End:    uint32(tf.Offset(cg.End())),
+		})
+	}
+	return ds
+}
@@ -0,0 +1,108 @@
+package srcco
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLoadGoProject analyzes the module in testdata/goproject, which
+// is the same project as testSources with a type and a method added.
+func TestLoadGoProject(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("testdata", "goproject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := testGenerator(t, Options{Dir: dir})
+	a, err := g.loadGoProject(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The files come package by package.
+	if got := strings.Join(a.files, ","); got != "main.go,greet/greet.go" {
+		t.Errorf("got files %s, want main.go,greet/greet.go", got)
+	}
+
+	src := testFile(t, dir, "greet/greet.go")
+	defs := map[string]def{}
+	for _, d := range a.defs["greet/greet.go"] {
+		defs[d.Path] = d
+	}
+	for _, want := range []struct {
+		path, kind, start string
+	}{
+		{"Greeter", "type", "type Greeter"},
+		{"Greeter/Name", "field", "Name string"},
+		{"Greeter/Greet", "method", "func (g Greeter)"},
+		{"Hello", "const", "const Hello"},
+	} {
+		d, ok := defs[want.path]
+		if !ok {
+			t.Errorf("no def for %s in %v", want.path, a.defs["greet/greet.go"])
+			continue
+		}
+		if d.Unit != "example.com/hello/greet" || d.Kind != want.kind {
+			t.Errorf("%s is a %s in %s, want a %s in example.com/hello/greet", want.path, d.Kind, d.Unit, want.kind)
+		}
+		if !strings.HasPrefix(src[d.DefStart:d.DefEnd], want.start) {
+			t.Errorf("%s covers %q, want it to start with %q", want.path, src[d.DefStart:d.DefEnd], want.start)
+		}
+	}
+	if d := defs["Greeter/Greet"]; d.Doc != "Greet returns the greeting." || d.Signature != "func (Greeter).Greet() string" {
+		t.Errorf("Greet has doc %q and signature %q", d.Doc, d.Signature)
+	}
+	if d := defs["Greeter/Name"]; d.Doc != "who to greet" {
+		t.Errorf("Name has doc %q, want the comment at the end of its line", d.Doc)
+	}
+
+	// The refs in main.go go to the defs in greet, and to fmt,
+	// which is in the standard library.
+	main := testFile(t, dir, "main.go")
+	refs := map[string]ref{}
+	for _, r := range a.refs["main.go"] {
+		refs[wordAt(main, r.Start)+"@"+r.DefUnit] = r
+	}
+	for _, want := range []string{
+		"Greeter@example.com/hello/greet",
+		"Name@example.com/hello/greet",
+		"Greet@example.com/hello/greet",
+		"Println@fmt",
+	} {
+		if _, ok := refs[want]; !ok {
+			t.Errorf("no ref to %s in main.go: %v", want, a.refs["main.go"])
+		}
+	}
+	if r := refs["Println@fmt"]; r.DefRepo != "" || r.DefPath != "Println" {
+		t.Errorf("the ref to fmt.Println is %+v", r)
+	}
+
+	// The comments at the start of a line are docs; the one after
+	// the code in main isn't.
+	var docs []string
+	for _, d := range a.docs["main.go"] {
+		docs = append(docs, main[d.Start:d.End])
+	}
+	if got := strings.Join(docs, "|"); got != "// Command hello says hello." {
+		t.Errorf("got docs %q in main.go, want just the package comment", got)
+	}
+	// They're plain text, so that they're rendered as Markdown
+	// like everyone else's.
+	if d := a.docs["main.go"][0]; d.Format != "text/plain" || d.Data != "Command hello says hello.\n" {
+		t.Errorf("got the %s doc %q for main.go, want the comment's plain text", d.Format, d.Data)
+	}
+	if n := len(a.docs["greet/greet.go"]); n != 4 {
+		t.Errorf("got %d docs in greet/greet.go, want 4", n)
+	}
+}
+
+// testFile reads the file name in dir.
+func testFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	b, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -0,0 +1,177 @@
+package srcco
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"path/filepath"
+)
+
+// htmlFormatter is the formatter for -format=html, srcco's own format:
+// a page for each file, with the docs next to the code, plus the
+// indexes and pages that the search and the backlinks need. It's the
+// only formatter that keeps a manifest (see manifest.go), so that it
+// only regenerates the pages that changed.
+type htmlFormatter struct {
+	g        *generator
+	out      Output
+	files    []string
+	fileDefs [][]def
+	defsMap  map[defKey]def
+	// outlines[i] is the HTML for the outline of the defs in
+	// files[i] (see outline.go).
+	outlines []string
+	// fileTOCs has the HTML for the file table of contents on a
+	// page with each resource prefix. fileTOCs[""] is the one for
+	// the pages at the top, like the search page.
+	fileTOCs map[string]string
+	m        *manifest
+	// hashes[i] is the hash of the source of files[i].
+	hashes  []string
+	entries []manifestEntry
+	// texts holds the text of each page, for the full-text index.
+	// It's separate from entries because we index the pages that
+	// we leave out of the manifest, too.
+	texts [][]textLine
+	// sites holds the refs in each page to the defs in the
+	// project, for the backlinks (see backlinks.go). Like texts,
+	// we keep them for every page.
+	sites [][]refSite
+}
+
+func (r *htmlFormatter) start(files []string, fileDefs [][]def, defsMap map[defKey]def) error {
+	r.files, r.fileDefs, r.defsMap = files, fileDefs, defsMap
+	r.outlines = make([]string, len(files))
+	for i := range files {
+		// We create the outline for the defs here. The
+		// TreePaths of the defs tell us how to nest them.
+		sortDefs(fileDefs[i])
+		r.outlines[i] = createOutline(defsTOCFilter(fileDefs[i]))
+	}
+	// The files are wrapped as pathers (which have the method
+	// path()) for createTableOfContents; the defs get an outline
+	// of their own. The pages work on the tables of contents at
+	// the same time, so we make them all now.
+	r.fileTOCs = map[string]string{}
+	for _, f := range append([]string{""}, files...) {
+		prefix := resourcePrefix(f)
+		if _, ok := r.fileTOCs[prefix]; ok {
+			continue
+		}
+		toc, err := createTableOfContents(filesWrapPathers(files), prefix)
+		if err != nil {
+			return err
+		}
+		r.fileTOCs[prefix] = toc
+	}
+
+	// We remember what we generated in a manifest, so that next
+	// time we only regenerate the pages whose source or defs
+	// changed. Every page is made from the assets and has the
+	// file table of contents in it, so if either of those changed
+	// (or a flag that changes the output), the old manifest is no
+	// good.
+	templateHash := hashBytes([]byte(r.g.assets.hash), []byte(r.fileTOCs[""]), []byte(fmt.Sprintf("sourcegraph=%v", r.g.EnableSourcegraphLinks)))
+	r.m = r.g.loadManifest(r.out)
+	r.g.removeStale(r.m, r.out, files)
+	if r.g.Force || r.m.Template != templateHash {
+		r.m = &manifest{Version: manifestVersion, Files: map[string]manifestEntry{}}
+	}
+	r.m.Template = templateHash
+	r.hashes = make([]string, len(files))
+	r.entries = make([]manifestEntry, len(files))
+	r.texts = make([][]textLine, len(files))
+	r.sites = make([][]refSite, len(files))
+	return nil
+}
+
+// Nothing below writes to the manifest until finish, so the pages can
+// share it.
+
+func (r *htmlFormatter) reuse(i int, src []byte) bool {
+	f := r.files[i]
+	r.hashes[i] = hashBytes(src)
+	if !r.m.upToDate(r.g.Dir, r.out, f, r.hashes[i], r.fileDefs[i], r.defsMap) {
+		return false
+	}
+	r.entries[i] = r.m.Files[f]
+	r.texts[i] = r.entries[i].Text
+	r.sites[i] = r.entries[i].Sites
+	return true
+}
+
+func (r *htmlFormatter) page(i int, src []byte, pg *page) error {
+	f := r.files[i]
+	r.texts[i] = segmentLines(pg.segments)
+	r.sites[i] = refSites(src, pg.refs, pg.segments, r.defsMap)
+	htmlFile := htmlFilename(f)
+	r.g.vLogf("Creating file %s", htmlFile)
+	// After gathering all that data, we feed it into our template!
+	prefix := resourcePrefix(f)
+	var b bytes.Buffer
+	if err := r.g.assets.code.Execute(&b, HTMLOutput{filepath.ToSlash(f), prefix, template.HTML(r.fileTOCs[prefix]), template.HTML(r.outlines[i]), pg.segments}); err != nil {
+		return err
+	}
+	if err := r.out.WriteFile(htmlFile, b.Bytes()); err != nil {
+		return err
+	}
+	// If the analysis was out of date, or the page had to make do
+	// without links, we leave it out of the manifest, so that we
+	// try again next time.
+	if pg.problem == nil && !pg.stale.stale() {
+		keys := refKeys(pg.refs)
+		r.entries[i] = manifestEntry{
+			Source: r.hashes[i],
+			Defs:   defsHash(r.fileDefs[i], keys, r.defsMap),
+			Refs:   keys,
+			Text:   r.texts[i],
+			Sites:  r.sites[i],
+		}
+		if len(pg.includes) != 0 {
+			r.entries[i].Includes = pg.includes
+		}
+	}
+	return nil
+}
+
+func (r *htmlFormatter) finish() error {
+	for i, f := range r.files {
+		if r.entries[i].Source == "" {
+			delete(r.m.Files, f)
+			continue
+		}
+		r.m.Files[f] = r.entries[i]
+	}
+	if err := r.m.save(r.out); err != nil {
+		return err
+	}
+	r.g.vLogf("Creating file %s", symbolIndexName)
+	if err := writeSymbolIndex(r.out, r.defsMap); err != nil {
+		return err
+	}
+	r.g.vLogf("Creating file %s", textIndexName)
+	if err := writeTextIndex(r.out, r.files, r.texts); err != nil {
+		return err
+	}
+	if err := writeSearchPage(r.out, r.g.assets.search, r.fileTOCs[""]); err != nil {
+		return err
+	}
+	r.g.vLogf("Creating backlinks in %s", backlinksDirName)
+	if err := writeBacklinks(r.out, r.files, r.sites, r.defsMap); err != nil {
+		return err
+	}
+	if err := writeBacklinksPage(r.out, r.g.assets.backlinks, r.fileTOCs[""]); err != nil {
+		return err
+	}
+	// We copy our resource files at the end.
+	return writeAssets(r.out, r.g.assets)
+}
+
+// writeAssets writes the stylesheet and the script that every page
+// uses.
+func writeAssets(out Output, a *assets) error {
+	if err := out.WriteFile("srcco.css", a.css); err != nil {
+		return err
+	}
+	return out.WriteFile("srcco.js", a.js)
+}
@@ -0,0 +1,158 @@
+package srcco
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// loadIndex reads the SCIP or LSIF index at file (relative to dir, or
+// absolute) for the project at dir. We tell the two apart by their
+// extensions.
+func (g *generator) loadIndex(dir, file string) (*analysis, error) {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(dir, file)
+	}
+	var a *analysis
+	var err error
+	switch ext := filepath.Ext(file); ext {
+	case ".scip":
+		a, err = g.loadSCIP(dir, file)
+	case ".lsif", ".json", ".jsonl":
+		a, err = g.loadLSIF(dir, file)
+	default:
+		return nil, fmt.Errorf("unknown index type %q (want a .scip or .lsif file)", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.hint = fmt.Sprintf("%s is probably out of date: rebuild it and run srcco again", file)
+	// Files that changed after the index was built might not
+	// match it anymore. We'll find out for sure when we check
+	// each file (see stale.go), but it's worth a warning up
+	// front.
+	if stale := staleFiles(dir, file, a.files); len(stale) != 0 {
+		g.logf("warning: %d files have changed since %s was built, so their links may be wrong. Rebuild the index to fix them.", len(stale), file)
+		for _, f := range stale {
+			g.vLogf("  %s", f)
+		}
+	}
+	return a, nil
+}
+
+// An index gives us positions as lines and characters, but srcco
+// works with byte offsets, so we need the source to convert between
+// the two. lineIndex remembers where every line starts in src.
+type lineIndex struct {
+	src    []byte
+	starts []int
+}
+
+func newLineIndex(src []byte) *lineIndex {
+	l := &lineIndex{src: src, starts: []int{0}}
+	for i, b := range src {
+		if b == '\n' {
+			l.starts = append(l.starts, i+1)
+		}
+	}
+	return l
+}
+
+// A posEncoding says what the character in a position counts.
+// Indexes get to choose: LSIF uses UTF-16 code units unless the dump
+// says otherwise, and a SCIP document says which it uses.
+type posEncoding int
+
+const (
+	utf8Units  posEncoding = iota // bytes
+	utf16Units                    // UTF-16 code units, like JavaScript strings
+	utf32Units                    // code points (runes)
+)
+
+// offset converts a 0-based line and character, counted in enc, into
+// a byte offset. ok is false if the position isn't in the file, which
+// usually means that the index is out of date.
+func (l *lineIndex) offset(line, char int, enc posEncoding) (off uint32, ok bool) {
+	if line < 0 || line >= len(l.starts) || char < 0 {
+		return 0, false
+	}
+	i := l.starts[line]
+	if enc == utf8Units {
+		if i+char > len(l.src) {
+			return 0, false
+		}
+		return uint32(i + char), true
+	}
+	for units := 0; units < char; {
+		if i >= len(l.src) || l.src[i] == '\n' {
+			return 0, false
+		}
+		r, size := utf8.DecodeRune(l.src[i:])
+		if enc == utf16Units && r >= 0x10000 {
+			units += 2
+		} else {
+			units++
+		}
+		i += size
+	}
+	return uint32(i), true
+}
+
+// lineStart returns the offset of the start of the line that off is
+// on.
+func (l *lineIndex) lineStart(off uint32) uint32 {
+	return uint32(bytes.LastIndexByte(l.src[:off], '\n') + 1)
+}
+
+// indexDoc creates a doc for a def at start out of the (Markdown)
+// documentation from an index. Indexes don't tell us where a def's
+// comment is, only what it says, so the doc doesn't cover any code:
+// it's empty and sits at the start of the def's line, which puts the
+// documentation next to the def. It's plain text, so genDocs renders
+// it as Markdown.
+func indexDoc(l *lineIndex, start uint32, markdown []string) doc {
+	ls := l.lineStart(start)
+	return doc{
+		Format: "text/plain",
+		Data:   strings.Join(markdown, "\n\n"),
+		Start:  ls,
+		End:    ls,
+	}
+}
+
+// indexSummary picks the signature and the doc summary for a hover
+// card out of the documentation from an index: the signature is the
+// first code block, and the summary is the first paragraph that isn't
+// code.
+func indexSummary(markdown []string) (signature, summary string) {
+	for _, md := range markdown {
+		var (
+			code, para []string
+			inCode     bool
+		)
+		for _, line := range strings.Split(md, "\n") {
+			switch {
+			case strings.HasPrefix(strings.TrimSpace(line), "```"):
+				if inCode && signature == "" {
+					signature = strings.TrimSpace(strings.Join(code, "\n"))
+				}
+				inCode, code = !inCode, nil
+			case inCode:
+				code = append(code, line)
+			case strings.TrimSpace(line) == "":
+				if summary == "" && len(para) != 0 {
+					summary = strings.Join(para, " ")
+				}
+				para = nil
+			default:
+				para = append(para, strings.TrimSpace(line))
+			}
+		}
+		if summary == "" && len(para) != 0 {
+			summary = strings.Join(para, " ")
+		}
+	}
+	return signature, summary
+}
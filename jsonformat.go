@@ -0,0 +1,224 @@
+package srcco
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// With -format=json, we write out what srcco worked out about the
+// project, instead of pages, so that you can build your own front end
+// on top of it. Each file gets a JSON document (a/b.go.json for a/b.go)
+// with its rows of docs and code, and srcco.json lists the files and
+// all of the defs in the project. The types below are the schema: a
+// change to them that could break a reader (anything but a new field)
+// bumps jsonSchemaVersion, which is in every document.
+//
+// Offsets are in bytes, and the offsets of the annotations in a
+// segment are from the start of the segment's code, so a reader
+// doesn't need the source to apply them.
+
+// jsonSchemaVersion is the version of the JSON format.
+const jsonSchemaVersion = 1
+
+// jsonIndexName is the name of the project document in the output
+// directory.
+const jsonIndexName = "srcco.json"
+
+// jsonProject is the project document.
+type jsonProject struct {
+	Version int         `json:"version"`
+	Files   []jsonEntry `json:"files"`
+	Defs    []jsonDef   `json:"defs"`
+}
+
+// A jsonEntry is a file in the project, and the name of its document,
+// relative to the output directory.
+type jsonEntry struct {
+	Path     string `json:"path"`
+	Document string `json:"document"`
+}
+
+// A jsonDef is a def in the project. ID is the anchor id that
+// annotations refer to it by, and Start and End are the offsets of the
+// whole def in File.
+type jsonDef struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Unit      string `json:"unit"`
+	Path      string `json:"path"`
+	TreePath  string `json:"treePath,omitempty"`
+	File      string `json:"file"`
+	Start     uint32 `json:"start"`
+	End       uint32 `json:"end"`
+	Signature string `json:"signature,omitempty"`
+	Doc       string `json:"doc,omitempty"`
+}
+
+// jsonFile is the document for one file. Its segments are the rows
+// that the HTML page has, in order.
+type jsonFile struct {
+	Version  int           `json:"version"`
+	Path     string        `json:"path"`
+	Segments []jsonSegment `json:"segments"`
+}
+
+// A jsonSegment is a row: the (sanitized) HTML for a doc, and the code
+// next to it. Start is the offset of Code in the file. Hidden is set
+// for code that a srcco:hide directive hides.
+type jsonSegment struct {
+	DocHTML     string           `json:"docHTML,omitempty"`
+	Code        string           `json:"code,omitempty"`
+	Start       int              `json:"start"`
+	Hidden      bool             `json:"hidden,omitempty"`
+	Annotations []jsonAnnotation `json:"annotations,omitempty"`
+}
+
+// A jsonAnnotation is an annotation on a segment's code (see codeAnn).
+// Class is the syntax highlighting class, if any. If the code refers
+// to a def in the project, Def is its id and File is its file; if it
+// refers to a def elsewhere, Href may link to it. Anchor is set for
+// the (empty) annotation where the def with that id starts.
+type jsonAnnotation struct {
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Class  string `json:"class,omitempty"`
+	Def    string `json:"def,omitempty"`
+	File   string `json:"file,omitempty"`
+	Href   string `json:"href,omitempty"`
+	Anchor string `json:"anchor,omitempty"`
+}
+
+// jsonFormatter is the formatter for -format=json. Like the Markdown
+// formatter, it doesn't keep a manifest.
+type jsonFormatter struct {
+	g       *generator
+	out     Output
+	files   []string
+	defsMap map[defKey]def
+	// written[i] is set once we've written the document for
+	// files[i].
+	written []bool
+}
+
+func (r *jsonFormatter) start(files []string, fileDefs [][]def, defsMap map[defKey]def) error {
+	r.files, r.defsMap = files, defsMap
+	r.written = make([]bool, len(files))
+	return nil
+}
+
+func (r *jsonFormatter) reuse(i int, src []byte) bool { return false }
+
+func (r *jsonFormatter) page(i int, src []byte, pg *page) error {
+	f := r.files[i]
+	r.g.vLogf("Creating file %s", f+".json")
+	if err := writeJSON(r.out, filepath.ToSlash(f)+".json", jsonFileFor(f, src, pg)); err != nil {
+		return err
+	}
+	r.written[i] = true
+	return nil
+}
+
+func (r *jsonFormatter) finish() error {
+	proj := jsonProject{Version: jsonSchemaVersion, Files: []jsonEntry{}, Defs: []jsonDef{}}
+	for i, f := range r.files {
+		if r.written[i] {
+			proj.Files = append(proj.Files, jsonEntry{Path: filepath.ToSlash(f), Document: filepath.ToSlash(f) + ".json"})
+		}
+	}
+	for _, d := range r.defsMap {
+		name := d.Name
+		if name == "" {
+			name = path.Base(d.Path)
+		}
+		proj.Defs = append(proj.Defs, jsonDef{
+			ID:        d.id(),
+			Name:      name,
+			Kind:      d.Kind,
+			Unit:      d.Unit,
+			Path:      d.Path,
+			TreePath:  d.TreePath,
+			File:      filepath.ToSlash(d.File),
+			Start:     d.DefStart,
+			End:       d.DefEnd,
+			Signature: d.Signature,
+			Doc:       d.Doc,
+		})
+	}
+	// The defs come out of a map, so we sort them to keep the
+	// document the same from run to run.
+	sort.Slice(proj.Defs, func(i, j int) bool {
+		if proj.Defs[i].File != proj.Defs[j].File {
+			return proj.Defs[i].File < proj.Defs[j].File
+		}
+		if proj.Defs[i].Start != proj.Defs[j].Start {
+			return proj.Defs[i].Start < proj.Defs[j].Start
+		}
+		return proj.Defs[i].ID < proj.Defs[j].ID
+	})
+	r.g.vLogf("Creating file %s", jsonIndexName)
+	return writeJSON(r.out, jsonIndexName, proj)
+}
+
+// jsonFileFor makes the document for the rendered file f.
+func jsonFileFor(f string, src []byte, pg *page) jsonFile {
+	jf := jsonFile{Version: jsonSchemaVersion, Path: filepath.ToSlash(f), Segments: []jsonSegment{}}
+	for _, s := range pg.segments {
+		js := jsonSegment{
+			DocHTML: string(s.DocHTML),
+			Code:    string(src[s.start:s.end]),
+			Start:   s.start,
+			Hidden:  s.Hidden,
+		}
+		// If the annotations didn't fit (see KeepGoing),
+		// the HTML doesn't have any, so we leave them out
+		// here too.
+		if pg.problem == nil {
+			for _, ca := range pg.anns {
+				if ca.start < s.start || ca.start >= s.end || ca.end > s.end {
+					continue
+				}
+				ja := jsonAnnotation{
+					Start:  ca.start - s.start,
+					End:    ca.end - s.start,
+					Class:  ca.class,
+					Def:    ca.def,
+					File:   filepath.ToSlash(ca.defFile),
+					Anchor: ca.anchor,
+				}
+				if ca.def == "" {
+					ja.Href = ca.href
+				}
+				js.Annotations = append(js.Annotations, ja)
+			}
+			// The anchors come before the tokens that
+			// start where they do.
+			sort.SliceStable(js.Annotations, func(i, j int) bool {
+				a, b := js.Annotations[i], js.Annotations[j]
+				if a.Start != b.Start {
+					return a.Start < b.Start
+				}
+				return a.Anchor != "" && b.Anchor == ""
+			})
+		}
+		jf.Segments = append(jf.Segments, js)
+	}
+	return jf
+}
+
+// writeJSON writes v to the file name in out as indented JSON. We
+// don't escape the HTML in it: the documents aren't going in a web
+// page, and the docs are easier to read without it.
+func writeJSON(out Output, name string, v interface{}) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return out.WriteFile(name, b.Bytes())
+}
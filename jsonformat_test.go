@@ -0,0 +1,45 @@
+package srcco
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// update tells the golden tests to write what they got to the golden
+// files, instead of checking it against them. Run
+//
+//	go test -run JSON -update
+//
+// after changing the JSON format on purpose, and check the diff.
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// TestJSONGolden checks the JSON export of testSources against the
+// files in testdata/json. A change to them is a change to the schema
+// that other front ends read, so if it could break them, it should
+// come with a new jsonSchemaVersion.
+func TestJSONGolden(t *testing.T) {
+	out := genTestDocs(t, Options{Format: "json"})
+	for _, name := range []string{jsonIndexName, "greet/greet.go.json"} {
+		got := readOutput(t, out, name)
+		golden := filepath.Join("testdata", "json", filepath.FromSlash(name))
+		if *update {
+			if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != string(want) {
+			t.Errorf("%s doesn't match %s (run with -update if the change is on purpose)\ngot:\n%s\nwant:\n%s", name, golden, got, want)
+		}
+	}
+}
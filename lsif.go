@@ -0,0 +1,464 @@
+package srcco
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// An LSIF dump (https://microsoft.github.io/language-server-protocol/specifications/lsif/0.6.0/specification/)
+// is a graph of vertices and edges. lsifElement has the fields of
+// every kind of element that we care about; which ones are set
+// depends on its Type and Label.
+type lsifElement struct {
+	ID    lsifID
+	Type  string
+	Label string
+
+	// metaData vertices
+	ProjectRoot      string
+	PositionEncoding string
+	// document vertices
+	URI string
+	// range vertices
+	Start, End lsifPosition
+	// Tag says what a range is, if the indexer tells us. Kind is
+	// an LSP SymbolKind.
+	Tag struct {
+		Type string
+		Kind int
+	}
+	// hoverResult vertices
+	Result struct {
+		Contents json.RawMessage
+	}
+	// moniker vertices
+	Scheme     string
+	Identifier string
+	Kind       string
+
+	// edges
+	OutV lsifID
+	InV  lsifID
+	InVs []lsifID
+}
+
+type lsifPosition struct {
+	Line      int
+	Character int
+}
+
+// lsifID is an element ID. The spec allows them to be numbers or
+// strings, so we store both as strings.
+type lsifID string
+
+func (id *lsifID) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*id = lsifID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*id = lsifID(n)
+	return nil
+}
+
+// less orders IDs by number if they're both numbers, so that 9 comes
+// before 10, and as strings otherwise.
+func (id lsifID) less(other lsifID) bool {
+	n, err1 := strconv.ParseUint(string(id), 10, 64)
+	m, err2 := strconv.ParseUint(string(other), 10, 64)
+	if err1 == nil && err2 == nil {
+		return n < m
+	}
+	return id < other
+}
+
+// lsifGraph is an LSIF dump, indexed so that we can follow its edges.
+type lsifGraph struct {
+	root      string
+	enc       posEncoding
+	documents map[lsifID]string
+	ranges    map[lsifID]lsifElement
+	hovers    map[lsifID]json.RawMessage
+	monikers  map[lsifID]lsifElement
+	// defResults is the set of definition result vertices.
+	defResults map[lsifID]bool
+	// rangeDocs is the document that contains each range.
+	rangeDocs map[lsifID]lsifID
+	// next, definition, hover and moniker are the edges with
+	// those labels, from their outV to their inV.
+	next, definition, hover, moniker map[lsifID]lsifID
+	// items are the ranges in each definition result.
+	items map[lsifID][]lsifID
+}
+
+// readLSIF reads the LSIF dump in b, which may be in JSON lines (the
+// usual format) or a single JSON array.
+func readLSIF(b []byte) ([]lsifElement, error) {
+	if b = bytes.TrimSpace(b); len(b) != 0 && b[0] == '[' {
+		var es []lsifElement
+		err := json.Unmarshal(b, &es)
+		return es, err
+	}
+	var es []lsifElement
+	s := bufio.NewScanner(bytes.NewReader(b))
+	s.Buffer(nil, 64*1024*1024)
+	for s.Scan() {
+		line := bytes.TrimSpace(s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var e lsifElement
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("bad LSIF element %q: %v", line, err)
+		}
+		es = append(es, e)
+	}
+	return es, s.Err()
+}
+
+func newLSIFGraph(dir string, es []lsifElement) *lsifGraph {
+	g := &lsifGraph{
+		root:       dir,
+		enc:        utf16Units,
+		documents:  map[lsifID]string{},
+		ranges:     map[lsifID]lsifElement{},
+		hovers:     map[lsifID]json.RawMessage{},
+		monikers:   map[lsifID]lsifElement{},
+		defResults: map[lsifID]bool{},
+		rangeDocs:  map[lsifID]lsifID{},
+		next:       map[lsifID]lsifID{},
+		definition: map[lsifID]lsifID{},
+		hover:      map[lsifID]lsifID{},
+		moniker:    map[lsifID]lsifID{},
+		items:      map[lsifID][]lsifID{},
+	}
+	for _, e := range es {
+		if e.Type == "edge" {
+			switch e.Label {
+			case "contains":
+				if _, ok := g.documents[e.OutV]; ok {
+					for _, v := range e.InVs {
+						g.rangeDocs[v] = e.OutV
+					}
+				}
+			case "next":
+				g.next[e.OutV] = e.InV
+			case "textDocument/definition":
+				g.definition[e.OutV] = e.InV
+			case "textDocument/hover":
+				g.hover[e.OutV] = e.InV
+			case "moniker":
+				g.moniker[e.OutV] = e.InV
+			case "item":
+				// Reference results have items too, but
+				// we only want the definitions.
+				if g.defResults[e.OutV] {
+					g.items[e.OutV] = append(g.items[e.OutV], e.InVs...)
+				}
+			}
+			continue
+		}
+		switch e.Label {
+		case "metaData":
+			if u, err := url.Parse(e.ProjectRoot); err == nil && u.Path != "" {
+				g.root = u.Path
+			}
+			// Positions are in UTF-16 code units unless
+			// the dump says otherwise.
+			switch e.PositionEncoding {
+			case "utf-8":
+				g.enc = utf8Units
+			case "utf-32":
+				g.enc = utf32Units
+			default:
+				g.enc = utf16Units
+			}
+		case "document":
+			g.documents[e.ID] = e.URI
+		case "range":
+			g.ranges[e.ID] = e
+		case "definitionResult":
+			g.defResults[e.ID] = true
+		case "hoverResult":
+			g.hovers[e.ID] = e.Result.Contents
+		case "moniker":
+			g.monikers[e.ID] = e
+		}
+	}
+	return g
+}
+
+// follow walks from the range or result set v along "next" edges
+// until it finds an edge in m, and returns where that edge goes.
+func (g *lsifGraph) follow(v lsifID, m map[lsifID]lsifID) (lsifID, bool) {
+	for seen := map[lsifID]bool{}; !seen[v]; {
+		seen[v] = true
+		if w, ok := m[v]; ok {
+			return w, true
+		}
+		next, ok := g.next[v]
+		if !ok {
+			break
+		}
+		v = next
+	}
+	return "", false
+}
+
+// file returns the name of the document d relative to the project
+// root, or false if it isn't in the project.
+func (g *lsifGraph) file(d lsifID) (string, bool) {
+	u, err := url.Parse(g.documents[d])
+	if err != nil || u.Path == "" {
+		return "", false
+	}
+	rel, err := filepath.Rel(g.root, u.Path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// loadLSIF reads an LSIF dump for the project at dir. Every range
+// with a definition becomes a ref to that definition, each
+// definition result becomes a def, and the hover text of a def
+// becomes its doc.
+func (gen *generator) loadLSIF(dir, indexFile string) (*analysis, error) {
+	gen.vLogf("Reading LSIF dump %s", indexFile)
+	b, err := ioutil.ReadFile(indexFile)
+	if err != nil {
+		return nil, err
+	}
+	es, err := readLSIF(b)
+	if err != nil {
+		return nil, err
+	}
+	g := newLSIFGraph(dir, es)
+
+	lines := map[string]*lineIndex{}
+	lineIndexFor := func(file string) *lineIndex {
+		if l, ok := lines[file]; ok {
+			return l
+		}
+		src, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			gen.vLogf("Skipping %s: %v", file, err)
+		}
+		// A nil lineIndex means we couldn't read the file.
+		var l *lineIndex
+		if err == nil {
+			l = newLineIndex(src)
+		}
+		lines[file] = l
+		return l
+	}
+	// rangeOffsets converts the range r into the file it's in and
+	// its byte offsets.
+	rangeOffsets := func(r lsifID) (file string, l *lineIndex, start, end uint32, ok bool) {
+		e, ok := g.ranges[r]
+		if !ok {
+			return "", nil, 0, 0, false
+		}
+		file, ok = g.file(g.rangeDocs[r])
+		if !ok {
+			return "", nil, 0, 0, false
+		}
+		if l = lineIndexFor(file); l == nil {
+			return "", nil, 0, 0, false
+		}
+		start, ok1 := l.offset(e.Start.Line, e.Start.Character, g.enc)
+		end, ok2 := l.offset(e.End.Line, e.End.Character, g.enc)
+		if !ok1 || !ok2 {
+			gen.vLogf("%s: range %s is out of range (is the dump stale?)", file, r)
+			return "", nil, 0, 0, false
+		}
+		return file, l, start, end, true
+	}
+
+	a := newAnalysis()
+	// First, we create a def for every definition result, keyed by
+	// its moniker if it has one (so that it's stable across
+	// dumps) and by its position if it doesn't.
+	defKeys := map[lsifID]defKey{}
+	var defResults []lsifID
+	for v, rs := range g.items {
+		// An item edge with no inVs leaves a definition
+		// result with nothing in it, so there's nothing to
+		// make a def from.
+		if len(rs) == 0 {
+			gen.vLogf("Skipping definition result %s: it has no items", v)
+			continue
+		}
+		defResults = append(defResults, v)
+	}
+	sort.Slice(defResults, func(i, j int) bool { return defResults[i].less(defResults[j]) })
+	for _, v := range defResults {
+		r := g.items[v][0]
+		file, l, start, end, ok := rangeOffsets(r)
+		if !ok {
+			continue
+		}
+		e := g.ranges[r]
+		key := defKey{Unit: file, Path: fmt.Sprintf("%d:%d", e.Start.Line+1, e.Start.Character+1)}
+		if m, ok := g.follow(r, g.moniker); ok {
+			if m := g.monikers[m]; m.Kind != "local" && m.Identifier != "" {
+				key = defKey{Unit: m.Scheme, Path: m.Identifier}
+			}
+		}
+		name := string(l.src[start:end])
+		defKeys[v] = key
+		d := def{
+			defKey:   key,
+			Name:     name,
+			File:     file,
+			DefStart: start,
+			DefEnd:   end,
+			TreePath: "./" + name,
+		}
+		if h, ok := g.follow(r, g.hover); ok {
+			if md := lsifHoverMarkdown(g.hovers[h]); len(md) != 0 {
+				a.docs[file] = append(a.docs[file], indexDoc(l, start, md))
+				d.Signature, d.Doc = indexSummary(md)
+			}
+		}
+		d.Kind = lsifKind(e.Tag.Kind, d.Signature)
+		a.defs[file] = append(a.defs[file], d)
+	}
+	// Every document in the project gets a page, even if none of
+	// its ranges fit it anymore.
+	files := map[string]bool{}
+	for d := range g.documents {
+		if file, ok := g.file(d); ok && lineIndexFor(file) != nil {
+			files[file] = true
+		}
+	}
+	// Then, every range that leads to a definition result is a ref
+	// to that def.
+	for r := range g.ranges {
+		file, _, start, _, ok := rangeOffsets(r)
+		if !ok {
+			continue
+		}
+		v, ok := g.follow(r, g.definition)
+		if !ok {
+			continue
+		}
+		key, ok := defKeys[v]
+		if !ok {
+			continue
+		}
+		a.refs[file] = append(a.refs[file], ref{
+			DefUnit: key.Unit,
+			DefPath: key.Path,
+			File:    file,
+			Start:   start,
+		})
+	}
+	for f := range files {
+		sort.Sort(refs(a.refs[f]))
+		a.files = append(a.files, f)
+	}
+	sort.Strings(a.files)
+	return a, nil
+}
+
+// lsifSymbolKinds names the LSP SymbolKinds that are defs, in the
+// words the other backends use where they have one.
+var lsifSymbolKinds = map[int]string{
+	2:  "module",
+	3:  "namespace",
+	4:  "package",
+	5:  "class",
+	6:  "method",
+	7:  "property",
+	8:  "field",
+	9:  "constructor",
+	10: "enum",
+	11: "interface",
+	12: "func",
+	13: "var",
+	14: "const",
+	22: "enum member",
+	23: "struct",
+	24: "event",
+	25: "operator",
+	26: "type parameter",
+}
+
+// lsifKind works out what kind of def a definition result is. The
+// range's tag tells us if the indexer wrote one; otherwise, we guess
+// from the keyword that the signature in its hover text starts with,
+// like "func" or "class". If neither helps, it's just a "symbol".
+func lsifKind(tagKind int, signature string) string {
+	if k, ok := lsifSymbolKinds[tagKind]; ok {
+		return k
+	}
+	fields := strings.Fields(signature)
+	if len(fields) == 0 {
+		return "symbol"
+	}
+	// TypeScript's hover text starts with the kind in parentheses,
+	// like "(method) A.m(): void".
+	switch kw := strings.Trim(fields[0], "()"); kw {
+	case "func", "function", "def", "fn":
+		// A Go method's signature has its receiver
+		// in parentheses after "func".
+		if kw == "func" && strings.HasPrefix(strings.TrimSpace(signature[len(kw):]), "(") {
+			return "method"
+		}
+		return "func"
+	case "let":
+		return "var"
+	case "type", "class", "interface", "struct", "enum", "trait", "var", "const",
+		"field", "method", "property", "module", "namespace", "package", "macro":
+		return kw
+	}
+	return "symbol"
+}
+
+// lsifHoverMarkdown pulls the Markdown out of the contents of a hover
+// result, which may be a MarkupContent, a MarkedString, or an array
+// of MarkedStrings.
+func lsifHoverMarkdown(contents json.RawMessage) []string {
+	type markedString struct {
+		Language string
+		Value    string
+	}
+	toMarkdown := func(raw json.RawMessage) (string, bool) {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return s, true
+		}
+		var m markedString
+		if err := json.Unmarshal(raw, &m); err != nil || m.Value == "" {
+			return "", false
+		}
+		if m.Language != "" {
+			return "```" + m.Language + "\n" + m.Value + "\n```", true
+		}
+		return m.Value, true
+	}
+	var many []json.RawMessage
+	if err := json.Unmarshal(contents, &many); err != nil {
+		many = []json.RawMessage{contents}
+	}
+	var md []string
+	for _, raw := range many {
+		if s, ok := toMarkdown(raw); ok {
+			md = append(md, s)
+		}
+	}
+	return md
+}
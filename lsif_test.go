@@ -0,0 +1,100 @@
+package srcco
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testLSIF is a dump of greet/greet.go in testSources with one def,
+// Hello (with hover text), and one ref to it. The second definition result has an item
+// edge with no inVs, which some indexers write for symbols they
+// couldn't resolve.
+const testLSIF = `{"id":1,"type":"vertex","label":"metaData","version":"0.4.3","projectRoot":"file://ROOT","positionEncoding":"utf-16"}
+{"id":2,"type":"vertex","label":"document","uri":"file://ROOT/greet/greet.go"}
+{"id":3,"type":"vertex","label":"range","start":{"line":4,"character":5},"end":{"line":4,"character":10}}
+{"id":4,"type":"vertex","label":"range","start":{"line":7,"character":6},"end":{"line":7,"character":14}}
+{"id":5,"type":"edge","label":"contains","outV":2,"inVs":[3,4]}
+{"id":6,"type":"vertex","label":"resultSet"}
+{"id":7,"type":"edge","label":"next","outV":3,"inV":6}
+{"id":8,"type":"vertex","label":"definitionResult"}
+{"id":9,"type":"edge","label":"textDocument/definition","outV":6,"inV":8}
+{"id":10,"type":"edge","label":"item","outV":8,"inVs":[3],"document":2}
+{"id":11,"type":"vertex","label":"definitionResult"}
+{"id":12,"type":"edge","label":"textDocument/definition","outV":4,"inV":11}
+{"id":13,"type":"edge","label":"item","outV":11,"inVs":[],"document":2}
+{"id":14,"type":"vertex","label":"hoverResult","result":{"contents":[{"language":"go","value":"func Hello()"},"Hello prints a greeting."]}}
+{"id":15,"type":"edge","label":"textDocument/hover","outV":6,"inV":14}
+`
+
+func TestLoadLSIFEmptyItems(t *testing.T) {
+	dir := testDir(t, testSources)
+	index := filepath.Join(t.TempDir(), "dump.lsif")
+	if err := ioutil.WriteFile(index, []byte(strings.Replace(testLSIF, "ROOT", filepath.ToSlash(dir), -1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	g := testGenerator(t, Options{Dir: dir, Verbose: true})
+	a, err := g.loadLSIF(dir, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := a.defs["greet/greet.go"]
+	if len(ds) != 1 || ds[0].Name != "Hello" {
+		t.Fatalf("got defs %v, want just Hello", ds)
+	}
+	if ds[0].Kind != "func" || ds[0].Signature != "func Hello()" {
+		t.Errorf("Hello is a %q with signature %q, want a func", ds[0].Kind, ds[0].Signature)
+	}
+	// The range whose definition result is empty isn't a ref to
+	// anything.
+	rs := a.refs["greet/greet.go"]
+	if len(rs) != 1 || rs[0].DefPath != ds[0].Path {
+		t.Errorf("got refs %v, want just the one to Hello", rs)
+	}
+}
+
+func TestLSIFKind(t *testing.T) {
+	for _, test := range []struct {
+		tagKind   int
+		signature string
+		want      string
+	}{
+		// The tag wins if there is one.
+		{12, "var f = func() {}", "func"},
+		{5, "", "class"},
+		{23, "", "struct"},
+		{6, "", "method"},
+		// Otherwise, we go by the hover text.
+		{0, "func Hello()", "func"},
+		{0, "func (g Greeter) Greet() string", "method"},
+		{0, "type Greeter struct{...}", "type"},
+		{0, "const Hello untyped string = \"hello\"", "const"},
+		{0, "field Name string", "field"},
+		{0, "function greet(name: string): string", "func"},
+		{0, "(method) Greeter.greet(): string", "method"},
+		{0, "class Greeter", "class"},
+		{0, "def greet(name)", "func"},
+		// And if that doesn't help, it's a symbol.
+		{0, "", "symbol"},
+		{0, "Greeter", "symbol"},
+		{1, "", "symbol"},
+	} {
+		if got := lsifKind(test.tagKind, test.signature); got != test.want {
+			t.Errorf("lsifKind(%d, %q) = %q, want %q", test.tagKind, test.signature, got, test.want)
+		}
+	}
+}
+
+func TestLSIFIDLess(t *testing.T) {
+	ids := []lsifID{"10", "9", "b", "100", "a", "2"}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].less(ids[j]) })
+	var got []string
+	for _, id := range ids {
+		got = append(got, string(id))
+	}
+	if strings.Join(got, ",") != "2,9,10,100,a,b" {
+		t.Errorf("got %v, want the numbers in order, then the strings", got)
+	}
+}
@@ -0,0 +1,175 @@
+package srcco
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+)
+
+// manifestName is the file in the output directory where we remember
+// what we generated last time, so that we only regenerate the pages
+// that changed.
+const manifestName = "srcco-manifest.json"
+
+// manifestVersion changes whenever srcco changes the way it renders
+// pages, which invalidates every page in an old manifest.
+const manifestVersion = 8
+
+type manifest struct {
+	Version int
+	// Template is the hash of everything that goes into every
+	// page: the template, the table of contents for the files and
+	// the flags that change the output.
+	Template string
+	// Files is keyed by file name.
+	Files map[string]manifestEntry
+}
+
+type manifestEntry struct {
+	// Source is the hash of the file's source code.
+	Source string
+	// Defs is the hash of the file's own defs and of the defs
+	// that its refs point to, as found by defsHash.
+	Defs string
+	// Refs are the keys of the defs that the file's refs point
+	// to. We need to remember them because we only know a file's
+	// refs after we've asked the provider for them, and that's
+	// exactly what we want to avoid doing for pages that haven't
+	// changed.
+	Refs []defKey
+	// Text is the text of the page, for the full-text index (see
+	// fulltext.go).
+	Text []textLine `json:",omitempty"`
+	// Sites are the refs in the page to the defs in the project,
+	// for the backlinks (see backlinks.go).
+	Sites []refSite `json:",omitempty"`
+	// Includes has the hash of the source of each file that the
+	// page includes code from (see directives.go), keyed by file
+	// name.
+	Includes map[string]string `json:",omitempty"`
+}
+
+// loadManifest reads the manifest in out. If there isn't one (or it's
+// from a different version of srcco), it returns an empty manifest,
+// which means everything gets regenerated.
+func (g *generator) loadManifest(out Output) *manifest {
+	m := &manifest{Version: manifestVersion, Files: map[string]manifestEntry{}}
+	b, err := fs.ReadFile(out, manifestName)
+	if err != nil {
+		return m
+	}
+	var old manifest
+	if err := json.Unmarshal(b, &old); err != nil || old.Version != manifestVersion || old.Files == nil {
+		g.vLogf("Ignoring the old manifest")
+		return m
+	}
+	return &old
+}
+
+func (m *manifest) save(out Output) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return out.WriteFile(manifestName, b)
+}
+
+// upToDate tells us whether the page for file can be left alone: its
+// source, its defs, the defs that it refers to and the files it
+// includes are the same as when we generated it, and it's still
+// there.
+func (m *manifest) upToDate(root string, out Output, file, source string, fileDefs []def, defsMap map[defKey]def) bool {
+	e, ok := m.Files[file]
+	if !ok || e.Source != source {
+		return false
+	}
+	if e.Defs != defsHash(fileDefs, e.Refs, defsMap) {
+		return false
+	}
+	for f, h := range e.Includes {
+		b, err := ioutil.ReadFile(filepath.Join(root, filepath.FromSlash(f)))
+		if err != nil || hashBytes(b) != h {
+			return false
+		}
+	}
+	_, err := fs.Stat(out, htmlFilename(file))
+	return err == nil
+}
+
+// removeStale deletes the pages for files that were in m but aren't
+// in files anymore.
+func (g *generator) removeStale(m *manifest, out Output, files []string) {
+	keep := map[string]bool{}
+	for _, f := range files {
+		keep[f] = true
+	}
+	for f := range m.Files {
+		if keep[f] {
+			continue
+		}
+		g.vLogf("Removing page for deleted file %s", f)
+		out.Remove(htmlFilename(f))
+		delete(m.Files, f)
+	}
+}
+
+// hashBytes returns the hex-encoded SHA-256 of b.
+func hashBytes(b ...[]byte) string {
+	h := sha256.New()
+	for _, b := range b {
+		h.Write(b)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// defsHash hashes a file's own defs, along with the current version
+// of each def that its refs point to. A ref that points to a def that
+// we don't know about is hashed too, so that the page is regenerated
+// if the def shows up later.
+func defsHash(fileDefs []def, refKeys []defKey, defsMap map[defKey]def) string {
+	h := sha256.New()
+	for _, d := range fileDefs {
+		writeDef(h, d)
+	}
+	io.WriteString(h, "\x00refs\x00")
+	for _, k := range refKeys {
+		if d, ok := defsMap[k]; ok {
+			writeDef(h, d)
+		} else {
+			fmt.Fprintf(h, "%s\x00%s\x00missing\n", k.Unit, k.Path)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeDef(w io.Writer, d def) {
+	fmt.Fprintf(w, "%s\x00%s\x00%s\x00%s\x00%s\x00%d\x00%d\x00%s\n",
+		d.Unit, d.Path, d.Name, d.Kind, d.File, d.DefStart, d.DefEnd, d.TreePath)
+}
+
+// refKeys returns the sorted, unique keys of the defs that rs point
+// to.
+func refKeys(rs []ref) []defKey {
+	seen := map[defKey]bool{}
+	var keys []defKey
+	for _, r := range rs {
+		k := defKey{r.DefUnit, r.DefPath}
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Unit != keys[j].Unit {
+			return keys[i].Unit < keys[j].Unit
+		}
+		return keys[i].Path < keys[j].Path
+	})
+	return keys
+}
@@ -0,0 +1,95 @@
+package srcco
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+
+	"github.com/sourcegraph/syntaxhighlight"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// Backends give us docs as HTML or as plain text. We render the plain
+// text ones as Markdown (CommonMark, plus GitHub's tables, strikeout,
+// task lists and autolinks), because that's what most people write
+// in their comments these days, and plain text is valid Markdown
+// anyway. The documentation in SCIP and LSIF indexes is Markdown too.
+//
+// goldmark leaves out any raw HTML in the Markdown, so a comment
+// can't put things on the page that it shouldn't.
+var md = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(
+		renderer.WithNodeRenderers(util.Prioritized(codeBlockRenderer{}, 100)),
+	),
+)
+
+// markdownHTML renders Markdown as HTML.
+func markdownHTML(text string) (string, error) {
+	var b bytes.Buffer
+	if err := md.Convert([]byte(text), &b); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// codeBlockRenderer renders the code blocks in Markdown with the same
+// syntax highlighting as the code column, so that examples in the
+// docs look like the code next to them.
+type codeBlockRenderer struct{}
+
+func (r codeBlockRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.renderCodeBlock)
+	reg.Register(ast.KindCodeBlock, r.renderCodeBlock)
+}
+
+func (r codeBlockRenderer) renderCodeBlock(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	var code bytes.Buffer
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		code.Write(line.Value(source))
+	}
+	w.WriteString("<pre><code")
+	if fc, ok := n.(*ast.FencedCodeBlock); ok {
+		if lang := fc.Language(source); len(lang) != 0 {
+			fmt.Fprintf(w, ` class="language-%s"`, template.HTMLEscapeString(string(lang)))
+		}
+	}
+	w.WriteString(">")
+	w.WriteString(highlightHTML(code.Bytes()))
+	w.WriteString("</code></pre>\n")
+	return ast.WalkSkipChildren, nil
+}
+
+// highlightHTML syntax highlights src, wrapping each token in a span
+// with the class that htmlAnnotator gives it, just like ann does for
+// the code column (but without any links). If the highlighter chokes
+// on src, we just escape it.
+func highlightHTML(src []byte) string {
+	anns, err := syntaxhighlight.Annotate(src, htmlAnnotator(syntaxhighlight.DefaultHTMLConfig))
+	if err != nil {
+		return template.HTMLEscapeString(string(src))
+	}
+	sort.Sort(anns)
+	var b bytes.Buffer
+	i := 0
+	for _, a := range anns {
+		if a.Start < i || a.End > len(src) {
+			continue
+		}
+		b.WriteString(template.HTMLEscapeString(string(src[i:a.Start])))
+		fmt.Fprintf(&b, `<span class="%s">%s</span>`, a.Left, template.HTMLEscapeString(string(src[a.Start:a.End])))
+		i = a.End
+	}
+	b.WriteString(template.HTMLEscapeString(string(src[i:])))
+	return b.String()
+}
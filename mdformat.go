@@ -0,0 +1,164 @@
+package srcco
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	htmltomd "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/JohannesKaufmann/html-to-markdown/plugin"
+)
+
+// With -format=markdown, we write each file as Markdown instead of
+// HTML, so that the docs can live in a wiki, or in the repository for
+// GitHub to render. The docs become Markdown prose, and the code goes
+// in fenced code blocks. A code block can't have links in it, so each
+// one has anchors for the defs in it above it, and links to the defs
+// that it uses below it. The page for a/b.go is a/b.go.md, and
+// README.md lists all of the pages.
+
+// markdownIndexName is the name of the list of pages in the output
+// directory.
+const markdownIndexName = "README.md"
+
+// htmlToMarkdown turns the HTML for a doc back into Markdown. By the
+// time we have a doc, it's HTML (that's what most backends give us,
+// and it's where the directives go), so we have to go the long way
+// round.
+var htmlToMarkdown = htmltomd.NewConverter("", true, &htmltomd.Options{CodeBlockStyle: "fenced"}).Use(plugin.GitHubFlavored())
+
+// markdownFormatter is the formatter for -format=markdown. It doesn't
+// keep a manifest: we generate every page, every time.
+type markdownFormatter struct {
+	g        *generator
+	out      Output
+	files    []string
+	fileDefs [][]def
+	defsMap  map[defKey]def
+	// written[i] is set once we've written the page for files[i].
+	written []bool
+}
+
+func (r *markdownFormatter) start(files []string, fileDefs [][]def, defsMap map[defKey]def) error {
+	r.files, r.fileDefs, r.defsMap = files, fileDefs, defsMap
+	r.written = make([]bool, len(files))
+	return nil
+}
+
+func (r *markdownFormatter) reuse(i int, src []byte) bool { return false }
+
+func (r *markdownFormatter) page(i int, src []byte, pg *page) error {
+	f := r.files[i]
+	b, err := r.g.markdownPage(f, src, pg, r.fileDefs[i], r.defsMap)
+	if err != nil {
+		return err
+	}
+	r.g.vLogf("Creating file %s", f+".md")
+	if err := r.out.WriteFile(filepath.ToSlash(f)+".md", b); err != nil {
+		return err
+	}
+	r.written[i] = true
+	return nil
+}
+
+func (r *markdownFormatter) finish() error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s\n\n", filepath.Base(r.g.Dir))
+	for i, f := range r.files {
+		if r.written[i] {
+			fmt.Fprintf(&b, "- [%s](%s)\n", f, filepath.ToSlash(f)+".md")
+		}
+	}
+	r.g.vLogf("Creating file %s", markdownIndexName)
+	return r.out.WriteFile(markdownIndexName, b.Bytes())
+}
+
+// markdownPage writes the rendered file f as Markdown.
+func (g *generator) markdownPage(f string, src []byte, pg *page, fileDefs []def, defsMap map[defKey]def) ([]byte, error) {
+	ds := append([]def(nil), fileDefs...)
+	sort.Slice(ds, func(i, j int) bool {
+		if ds[i].DefStart != ds[j].DefStart {
+			return ds[i].DefStart < ds[j].DefStart
+		}
+		return ds[i].Path < ds[j].Path
+	})
+	lang := strings.TrimPrefix(filepath.Ext(f), ".")
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s\n\n", f)
+	for _, s := range pg.segments {
+		if s.DocHTML != "" {
+			text, err := htmlToMarkdown.ConvertString(string(s.DocHTML))
+			if err != nil {
+				return nil, err
+			}
+			if text = strings.TrimSpace(text); text != "" {
+				b.WriteString(text)
+				b.WriteString("\n\n")
+			}
+		}
+		code := strings.Trim(string(src[s.start:s.end]), "\n")
+		if strings.TrimSpace(code) == "" {
+			continue
+		}
+		// The anchors for the defs in the code go above it.
+		anchors := 0
+		for _, d := range ds {
+			if s.start <= int(d.DefStart) && int(d.DefStart) < s.end {
+				fmt.Fprintf(&b, `<a id="%s"></a>`, html.EscapeString(d.id()))
+				anchors++
+			}
+		}
+		if anchors != 0 {
+			b.WriteString("\n\n")
+		}
+		if s.Hidden {
+			b.WriteString("<details><summary>Code hidden</summary>\n\n")
+		}
+		// The fence has to be longer than any run of backticks
+		// in the code.
+		fence := "```"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		fmt.Fprintf(&b, "%s%s\n%s\n%s\n\n", fence, lang, code, fence)
+		if s.Hidden {
+			b.WriteString("</details>\n\n")
+		}
+		if uses := g.markdownUses(f, s, pg.refs, defsMap); len(uses) != 0 {
+			fmt.Fprintf(&b, "Uses %s.\n\n", strings.Join(uses, ", "))
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// markdownUses returns links to the defs in the project that the code
+// in s refers to, in the order it first refers to them. We leave out
+// the defs in s itself, because their anchors are right there.
+func (g *generator) markdownUses(f string, s segment, rs []ref, defsMap map[defKey]def) []string {
+	var uses []string
+	seen := map[defKey]bool{}
+	for _, r := range rs {
+		if int(r.Start) < s.start || int(r.Start) >= s.end {
+			continue
+		}
+		d, ok := defsMap[defKey{r.DefUnit, r.DefPath}]
+		if !ok || seen[d.defKey] {
+			continue
+		}
+		seen[d.defKey] = true
+		if d.File == f && s.start <= int(d.DefStart) && int(d.DefStart) < s.end {
+			continue
+		}
+		name := d.Name
+		if name == "" {
+			name = path.Base(d.Path)
+		}
+		uses = append(uses, fmt.Sprintf("[`%s`](%s)", name, g.defHref(f, d.File, d.id())))
+	}
+	return uses
+}
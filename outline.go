@@ -0,0 +1,171 @@
+package srcco
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+)
+
+// The outline is the panel next to the code that lists the defs in a
+// file. Defs are grouped by kind (types, then funcs, and so on), and
+// each def has the defs that belong to it nested underneath, like a
+// type's fields and methods. srcco.js makes the nested lists
+// collapsible, and highlights the def that you've scrolled to.
+
+// outlineNode is a def in the outline, along with the defs nested
+// under it. def is nil if the def isn't in this file, like the type
+// of a method that's declared somewhere else; we still need a node
+// to put the method under.
+type outlineNode struct {
+	name     string
+	kind     string
+	def      *def
+	children []*outlineNode
+}
+
+// outlineKinds is the order that the groups of defs appear in.
+// Groups for other kinds come after these, in alphabetical order.
+var outlineKinds = []string{"type", "interface", "class", "func", "method", "var", "const", "field"}
+
+// createOutline creates the outline for a file's defs. The TreePaths
+// of the defs tell us how to nest them: "T/M" goes under "T".
+func createOutline(ds []def) string {
+	if len(ds) == 0 {
+		return ""
+	}
+	// We list the defs in the order they appear in the file, so
+	// that the outline reads in the same order as the code.
+	ds = append([]def(nil), ds...)
+	sort.SliceStable(ds, func(i, j int) bool { return ds[i].DefStart < ds[j].DefStart })
+
+	nodes := map[string]*outlineNode{}
+	var top []*outlineNode
+	// node returns the node for the tree path p, creating it (and
+	// its parents) if we haven't seen it yet.
+	var node func(p string) *outlineNode
+	node = func(p string) *outlineNode {
+		if n, ok := nodes[p]; ok {
+			return n
+		}
+		// A node that we create before (or without) its def is
+		// most likely a type with methods in this file.
+		n := &outlineNode{name: p[strings.LastIndex(p, "/")+1:], kind: "type"}
+		nodes[p] = n
+		if i := strings.LastIndex(p, "/"); i >= 0 {
+			parent := node(p[:i])
+			parent.children = append(parent.children, n)
+		} else {
+			top = append(top, n)
+		}
+		return n
+	}
+	for i := range ds {
+		d := &ds[i]
+		p := strings.TrimPrefix(d.TreePath, "./")
+		if p == "" {
+			continue
+		}
+		n := node(p)
+		if n.def != nil {
+			// Two defs with the same tree path (an
+			// overloaded function, say) get a node each.
+			n = &outlineNode{name: n.name}
+			if i := strings.LastIndex(p, "/"); i >= 0 {
+				parent := node(p[:i])
+				parent.children = append(parent.children, n)
+			} else {
+				top = append(top, n)
+			}
+		}
+		n.def = d
+		n.kind = d.Kind
+		if d.Name != "" {
+			n.name = d.Name
+		}
+	}
+
+	groups := map[string][]*outlineNode{}
+	var kinds []string
+	for _, n := range top {
+		if _, ok := groups[n.kind]; !ok {
+			kinds = append(kinds, n.kind)
+		}
+		groups[n.kind] = append(groups[n.kind], n)
+	}
+	sort.Slice(kinds, func(i, j int) bool {
+		ri, rj := kindRank(kinds[i]), kindRank(kinds[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return kinds[i] < kinds[j]
+	})
+
+	var b bytes.Buffer
+	b.WriteString(`<div class="outline">`)
+	for _, k := range kinds {
+		fmt.Fprintf(&b, `<div class="outline-group"><div class="outline-kind">%s</div><ul>`, html.EscapeString(pluralKind(k)))
+		for _, n := range groups[k] {
+			writeOutlineNode(&b, n)
+		}
+		b.WriteString(`</ul></div>`)
+	}
+	b.WriteString(`</div>`)
+	return b.String()
+}
+
+func kindRank(kind string) int {
+	for i, k := range outlineKinds {
+		if k == kind {
+			return i
+		}
+	}
+	return len(outlineKinds)
+}
+
+// pluralKind turns a kind into a heading for its group.
+func pluralKind(kind string) string {
+	switch {
+	case kind == "":
+		return "other"
+	case strings.HasSuffix(kind, "s"), strings.HasSuffix(kind, "x"),
+		strings.HasSuffix(kind, "ch"), strings.HasSuffix(kind, "sh"):
+		return kind + "es"
+	}
+	return kind + "s"
+}
+
+// writeOutlineNode writes n as a list item. Nodes with children start
+// out collapsed; srcco.js opens them when you click on their toggle
+// or scroll to one of their children.
+func writeOutlineNode(b *bytes.Buffer, n *outlineNode) {
+	class := "outline-node"
+	if len(n.children) != 0 {
+		class += " collapsed"
+	}
+	fmt.Fprintf(b, `<li class="%s"><div class="outline-title">`, class)
+	if len(n.children) != 0 {
+		b.WriteString(`<i class="fa fa-angle-right outline-toggle"></i>`)
+	} else {
+		b.WriteString(`<i class="outline-leaf"></i>`)
+	}
+	if n.def != nil {
+		id := n.def.id()
+		fmt.Fprintf(b, `<a href="#%s" data-def="%s">%s</a>`, html.EscapeString(id), html.EscapeString(id), html.EscapeString(n.name))
+	} else {
+		fmt.Fprintf(b, `<span>%s</span>`, html.EscapeString(n.name))
+	}
+	if n.kind != "" {
+		fmt.Fprintf(b, ` <span class="outline-def-kind">%s</span>`, html.EscapeString(n.kind))
+	}
+	b.WriteString(`</div>`)
+	if len(n.children) != 0 {
+		b.WriteString(`<ul>`)
+		for _, c := range n.children {
+			writeOutlineNode(b, c)
+		}
+		b.WriteString(`</ul>`)
+	}
+	b.WriteString(`</li>`)
+}
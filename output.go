@@ -0,0 +1,128 @@
+package srcco
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing/fstest"
+)
+
+// Generate writes the docs to an Output. Usually that's a directory
+// (OutDir, or the gh-pages branch), but it can be anything that holds
+// files, like memory, which is handy for tests.
+
+// An Output is where the docs go. Names are slash-separated paths
+// relative to the root of the docs, like the names in an fs.FS. We
+// write -j pages at a time, so the methods have to be safe to call
+// from more than one goroutine.
+type Output interface {
+	// Open opens a file that's already in the output, like the
+	// manifest from the last run (see manifest.go). An output
+	// that always starts out empty can say that nothing exists.
+	fs.FS
+	// WriteFile writes data to the file name, creating the
+	// directories it's in.
+	WriteFile(name string, data []byte) error
+	// Remove removes the file or directory name, and everything
+	// in it. It isn't an error if there's nothing to remove.
+	Remove(name string) error
+}
+
+// DirOutput returns an Output that writes to the directory dir.
+func DirOutput(dir string) Output {
+	return dirOutput(dir)
+}
+
+type dirOutput string
+
+func (d dirOutput) path(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(string(d), filepath.FromSlash(name)), nil
+}
+
+func (d dirOutput) Open(name string) (fs.File, error) {
+	return os.DirFS(string(d)).Open(name)
+}
+
+func (d dirOutput) WriteFile(name string, data []byte) error {
+	file, err := d.path("write", name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+func (d dirOutput) Remove(name string) error {
+	file, err := d.path("remove", name)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(file)
+}
+
+// A MemOutput is an Output that keeps the docs in memory. The zero
+// value is an empty output.
+type MemOutput struct {
+	mu    sync.Mutex
+	files fstest.MapFS
+}
+
+func (m *MemOutput) Open(name string) (fs.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.files.Open(name)
+}
+
+func (m *MemOutput) WriteFile(name string, data []byte) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.files == nil {
+		m.files = fstest.MapFS{}
+	}
+	m.files[name] = &fstest.MapFile{Data: append([]byte(nil), data...), Mode: 0644}
+	return nil
+}
+
+func (m *MemOutput) Remove(name string) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for f := range m.files {
+		if f == name || name == "." || strings.HasPrefix(f, name+"/") {
+			delete(m.files, f)
+		}
+	}
+	return nil
+}
+
+// Files returns the names of the files in m, sorted.
+func (m *MemOutput) Files() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sortedFiles()
+}
+
+// sortedFiles returns the names of the files in m, sorted. The caller
+// has to hold m.mu.
+func (m *MemOutput) sortedFiles() []string {
+	names := make([]string, 0, len(m.files))
+	for f := range m.files {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+	return names
+}
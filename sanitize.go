@@ -0,0 +1,34 @@
+package srcco
+
+import (
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// Docs come to us as HTML, and the HTML comes from whoever wrote the
+// comments in the project. Published docs shouldn't run a script just
+// because someone put one in a comment, so every doc goes through
+// docPolicy before it goes on a page. The policy lets through the
+// sort of HTML that godoc and Markdown produce (paragraphs, headings,
+// lists, tables, links, code), plus the classes that our syntax
+// highlighting uses, and throws away everything else.
+var docPolicy = newDocPolicy()
+
+func newDocPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	// godoc gives headings ids so that you can link to them.
+	p.AllowAttrs("id").Matching(regexp.MustCompile(`^[\w\-.:]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	// Code blocks have a language class, and the tokens in them
+	// have the classes from htmlAnnotator.
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^[\w\- ]+$`)).OnElements("code", "pre", "span")
+	// Links in docs to other sites open there, not in place of
+	// the docs, and they don't get our page as the referrer.
+	p.RequireNoReferrerOnFullyQualifiedLinks(true)
+	return p
+}
+
+// sanitizeDocHTML makes the HTML for a doc safe to put on a page.
+func sanitizeDocHTML(h string) string {
+	return docPolicy.Sanitize(h)
+}
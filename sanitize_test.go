@@ -0,0 +1,145 @@
+package srcco
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// hostileDocs are comments written to run a script on the page. None
+// of them should make it through with anything that can.
+var hostileDocs = []struct {
+	name, html string
+}{
+	{"script", `<p>Hi</p><script>alert(1)</script>`},
+	{"script in code", `<pre><code><script>alert(1)</script></code></pre>`},
+	{"onerror", `<img src="x" onerror="alert(1)">`},
+	{"onclick", `<p onclick="alert(1)">Click me</p>`},
+	{"onmouseover on a link", `<a href="https://example.com/" onmouseover="alert(1)">x</a>`},
+	{"javascript URL", `<a href="javascript:alert(1)">x</a>`},
+	{"javascript URL with entities", `<a href="jav&#x09;ascript:alert(1)">x</a>`},
+	{"data URL", `<a href="data:text/html,<script>alert(1)</script>">x</a>`},
+	{"iframe", `<iframe src="https://example.com/"></iframe>`},
+	{"style", `<style>body { display: none; }</style><p style="background: url(javascript:alert(1))">x</p>`},
+	{"svg", `<svg><script>alert(1)</script></svg>`},
+	{"form", `<form action="https://example.com/"><input type="submit"></form>`},
+	{"unclosed attribute", `<p title="x><script>alert(1)</script>">x</p>`},
+}
+
+// checkHarmless fails the test if h has anything in it that could run
+// a script. Text is escaped, so it's harmless whatever it says; we only
+// have to look in the tags.
+func checkHarmless(t *testing.T, name, h string) {
+	t.Helper()
+	for _, tag := range tagRE.FindAllString(h, -1) {
+		if badTagRE.MatchString(tag) {
+			t.Errorf("%s: %q has the tag %s in it", name, h, tag)
+		}
+	}
+}
+
+var (
+	tagRE = regexp.MustCompile(`<[^>]*>`)
+	// docColumnRE matches the doc column of a row, which
+	// doesn't have any divs inside it.
+	docColumnRE = regexp.MustCompile(`(?s)<div class="doc">.*?</div>`)
+	badTagRE    = regexp.MustCompile(`(?i)^</?(script|iframe|style|svg|form|input|object|embed)\b|\son\w+\s*=|\sstyle\s*=|javascript:|data:`)
+)
+
+func TestSanitizeDocHTML(t *testing.T) {
+	for _, d := range hostileDocs {
+		checkHarmless(t, d.name, sanitizeDocHTML(d.html))
+	}
+}
+
+func TestSanitizeDocHTMLKeepsDocs(t *testing.T) {
+	// The HTML that godoc and Markdown make should come through
+	// as it was.
+	for _, h := range []string{
+		`<h3 id="hdr-Usage">Usage</h3>`,
+		`<p>See <code>F</code> and <em>G</em>.</p>`,
+		`<ul><li>one</li><li>two</li></ul>`,
+		`<pre><code class="language-go"><span class="kwd">func</span></code></pre>`,
+		`<table><thead><tr><th>a</th></tr></thead><tbody><tr><td>1</td></tr></tbody></table>`,
+	} {
+		if got := sanitizeDocHTML(h); got != h {
+			t.Errorf("sanitizeDocHTML(%q) = %q, want it unchanged", h, got)
+		}
+	}
+	// Links to other sites don't tell them where you came from.
+	got := sanitizeDocHTML(`<a href="https://example.com/">x</a>`)
+	if !strings.Contains(got, `noreferrer`) {
+		t.Errorf("got %q, want a noreferrer link", got)
+	}
+}
+
+func TestMarkdownHTMLHostile(t *testing.T) {
+	for _, d := range []struct {
+		name, md string
+	}{
+		{"raw script", "Hi\n\n<script>alert(1)</script>\n"},
+		{"inline script", "Hi <script>alert(1)</script> there"},
+		{"raw onerror", `<img src="x" onerror="alert(1)">`},
+		{"inline onclick", `Hi <span onclick="alert(1)">there</span>`},
+		{"javascript link", "[x](javascript:alert(1))"},
+		{"javascript autolink", "<javascript:alert(1)>"},
+		{"javascript image", "![x](javascript:alert(1))"},
+		{"data link", "[x](data:text/html,alert(1))"},
+		{"iframe", `<iframe src="https://example.com/"></iframe>`},
+	} {
+		h, err := markdownHTML(d.md)
+		if err != nil {
+			t.Errorf("%s: %v", d.name, err)
+			continue
+		}
+		// markdownHTML leaves out raw HTML by itself, and
+		// every doc goes through sanitizeDocHTML after it
+		// (see renderPage), so we check both.
+		checkHarmless(t, d.name, h)
+		checkHarmless(t, d.name, sanitizeDocHTML(h))
+	}
+}
+
+func TestMarkdownHTML(t *testing.T) {
+	h, err := markdownHTML("Use `F` to *frob*.\n\n- [docs](https://example.com/)\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`<code>F</code>`, `<em>frob</em>`, `<a href="https://example.com/">docs</a>`} {
+		if !strings.Contains(h, s) {
+			t.Errorf("markdownHTML = %q, want %s in it", h, s)
+		}
+	}
+}
+
+// The docs on a page go through the same sanitizing, whatever format
+// the backend gives them to us in.
+func TestPageSanitizesDocs(t *testing.T) {
+	opts := Options{Dir: testDir(t, testSources)}
+	g := testGenerator(t, opts)
+	a := testAnalysis(t)
+	greet := a.docs["greet/greet.go"]
+	greet[1].Format = "text/html"
+	greet[1].Data = `<p onclick="alert(1)">Hello prints a <a href="javascript:alert(1)">greeting</a>.</p><script>alert(1)</script>`
+	greet[2].Data = "Greeting is <img src=x onerror=alert(1)> what [Hello](javascript:alert(1)) says."
+	var out MemOutput
+	if err := g.genDocs(context.Background(), a, &out, a.files); err != nil {
+		t.Fatal(err)
+	}
+	page := readOutput(t, &out, "greet/greet.go.html")
+	// The page has its own script and search box, so we only
+	// look at the docs.
+	docs := docColumnRE.FindAllString(page, -1)
+	if len(docs) != 3 {
+		t.Fatalf("got %d docs, want 3:\n%s", len(docs), page)
+	}
+	for _, d := range docs {
+		checkHarmless(t, "greet/greet.go.html", d)
+	}
+	for _, s := range []string{"Hello prints a greeting.", "Greeting is", "says."} {
+		if !strings.Contains(page, s) {
+			t.Errorf("greet/greet.go.html lost %q from the docs:\n%s", s, page)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package srcco
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sourcegraph/scip/bindings/go/scip"
+	"google.golang.org/protobuf/proto"
+)
+
+// loadSCIP reads a SCIP index (https://github.com/sourcegraph/scip)
+// for the project at dir. Every occurrence of a symbol becomes a ref,
+// occurrences that define a symbol also become defs, and the
+// documentation for those symbols becomes docs.
+func (g *generator) loadSCIP(dir, indexFile string) (*analysis, error) {
+	g.vLogf("Reading SCIP index %s", indexFile)
+	b, err := ioutil.ReadFile(indexFile)
+	if err != nil {
+		return nil, err
+	}
+	var index scip.Index
+	if err := proto.Unmarshal(b, &index); err != nil {
+		return nil, err
+	}
+	return g.scipAnalysis(dir, &index), nil
+}
+
+// scipAnalysis turns a SCIP index for the project at dir into an
+// analysis.
+func (g *generator) scipAnalysis(dir string, index *scip.Index) *analysis {
+	a := newAnalysis()
+	for _, d := range index.Documents {
+		file := filepath.ToSlash(d.RelativePath)
+		src, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			g.vLogf("Skipping %s: %v", file, err)
+			continue
+		}
+		l := newLineIndex(src)
+		enc := scipEncoding(d.PositionEncoding)
+		documentation := map[string][]string{}
+		for _, s := range d.Symbols {
+			documentation[s.Symbol] = s.Documentation
+		}
+		a.files = append(a.files, file)
+		for _, o := range d.Occurrences {
+			if scip.IsLocalSymbol(o.Symbol) {
+				continue
+			}
+			key, name, kind, ok := scipDefKey(o.Symbol)
+			if !ok {
+				continue
+			}
+			start, end, ok := scipRange(l, o.Range, enc)
+			if !ok {
+				g.vLogf("%s: occurrence of %s is out of range (is the index stale?)", file, o.Symbol)
+				continue
+			}
+			a.refs[file] = append(a.refs[file], ref{
+				DefUnit: key.Unit,
+				DefPath: key.Path,
+				File:    file,
+				Start:   start,
+			})
+			if o.SymbolRoles&int32(scip.SymbolRole_Definition) == 0 {
+				continue
+			}
+			// A def covers its whole declaration if the
+			// indexer tells us what that is.
+			if s, e, ok := scipRange(l, o.EnclosingRange, enc); ok {
+				start, end = s, e
+			}
+			sig, summary := indexSummary(documentation[o.Symbol])
+			a.defs[file] = append(a.defs[file], def{
+				defKey:    key,
+				Name:      name,
+				Kind:      kind,
+				File:      file,
+				DefStart:  start,
+				DefEnd:    end,
+				TreePath:  "./" + scipTreePath(o.Symbol),
+				Signature: sig,
+				Doc:       summary,
+			})
+			if docs := documentation[o.Symbol]; len(docs) != 0 {
+				a.docs[file] = append(a.docs[file], indexDoc(l, start, docs))
+			}
+		}
+	}
+	sort.Strings(a.files)
+	return a
+}
+
+// scipEncoding converts a document's position encoding. Indexers that
+// are older than the encoding field count bytes, like scip-go does.
+func scipEncoding(e scip.PositionEncoding) posEncoding {
+	switch e {
+	case scip.PositionEncoding_UTF16CodeUnitOffsetFromLineStart:
+		return utf16Units
+	case scip.PositionEncoding_UTF32CodeUnitOffsetFromLineStart:
+		return utf32Units
+	}
+	return utf8Units
+}
+
+// scipRange converts a SCIP range, which is either [startLine,
+// startCharacter, endCharacter] or [startLine, startCharacter,
+// endLine, endCharacter], into byte offsets. The characters are
+// counted in enc.
+func scipRange(l *lineIndex, r []int32, enc posEncoding) (start, end uint32, ok bool) {
+	var endLine, endChar int32
+	switch len(r) {
+	case 3:
+		endLine, endChar = r[0], r[2]
+	case 4:
+		endLine, endChar = r[2], r[3]
+	default:
+		return 0, 0, false
+	}
+	start, ok = l.offset(int(r[0]), int(r[1]), enc)
+	if !ok {
+		return 0, 0, false
+	}
+	end, ok = l.offset(int(endLine), int(endChar), enc)
+	return start, end, ok
+}
+
+// scipDefKey turns a SCIP symbol like
+//
+//	scip-go gomod github.com/a/b v1.0 `github.com/a/b/c`/T#M().
+//
+// into a def key, where the unit is the package name
+// ("github.com/a/b") and the path is made up of the descriptors
+// ("github.com/a/b/c/T/M"). It also returns the name and kind of the
+// def. Parameters aren't defs, just like locals, and neither are
+// packages, because we'd get one for every file.
+func scipDefKey(symbol string) (key defKey, name, kind string, ok bool) {
+	s, err := scip.ParseSymbol(symbol)
+	if err != nil || len(s.Descriptors) == 0 {
+		return defKey{}, "", "", false
+	}
+	if s.Package != nil && s.Package.Name != "" && s.Package.Name != "." {
+		key.Unit = s.Package.Name
+	} else {
+		key.Unit = s.Scheme
+	}
+	var parts []string
+	for _, d := range s.Descriptors {
+		parts = append(parts, d.Name)
+	}
+	key.Path = strings.Join(parts, "/")
+	last := s.Descriptors[len(s.Descriptors)-1]
+	switch last.Suffix {
+	case scip.Descriptor_Type:
+		kind = "type"
+	case scip.Descriptor_Term:
+		kind = "var"
+	case scip.Descriptor_Method:
+		kind = "method"
+	case scip.Descriptor_Macro:
+		kind = "macro"
+	case scip.Descriptor_Meta:
+		kind = "meta"
+	default:
+		return defKey{}, "", "", false
+	}
+	return key, last.Name, kind, true
+}
+
+// scipTreePath is the path of a symbol within its namespace, e.g.
+// "T/M" for the symbol in scipDefKey's example. It's what we build
+// the per-file table of contents from.
+func scipTreePath(symbol string) string {
+	s, err := scip.ParseSymbol(symbol)
+	if err != nil {
+		return ""
+	}
+	var parts []string
+	for _, d := range s.Descriptors {
+		if d.Suffix != scip.Descriptor_Namespace {
+			parts = append(parts, d.Name)
+		}
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,157 @@
+package srcco
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/sourcegraph/scip/bindings/go/scip"
+)
+
+// testSCIPSource has non-ASCII text before a ref on the same line, so
+// that bytes, UTF-16 code units and code points all give different
+// positions for it. The emoji takes two UTF-16 code units.
+const testSCIPSource = `package greet
+
+// Hello says hello.
+func Hello() string { return "😀 héllo, 世界 " + Name }
+
+// Name is who to greet.
+var Name = "world"
+`
+
+// scipPos returns the position of the first s after after in src, as
+// SCIP would write it in enc.
+func scipPos(t *testing.T, src, after, s string, enc posEncoding) (line, char int32) {
+	t.Helper()
+	off := int(offset(t, src, after, s))
+	lineStart := strings.LastIndexByte(src[:off], '\n') + 1
+	prefix := src[lineStart:off]
+	switch enc {
+	case utf8Units:
+		char = int32(len(prefix))
+	case utf16Units:
+		for _, r := range prefix {
+			char++
+			if r >= 0x10000 {
+				char++
+			}
+		}
+	case utf32Units:
+		char = int32(utf8.RuneCountInString(prefix))
+	}
+	return int32(strings.Count(src[:off], "\n")), char
+}
+
+// testSCIPIndex is what an indexer would write for testSCIPSource,
+// counting characters in enc.
+func testSCIPIndex(t *testing.T, enc posEncoding, scipEnc scip.PositionEncoding) *scip.Index {
+	const (
+		hello = "scip-go gomod example.com/hello v1.0.0 `example.com/hello/greet`/Hello()."
+		name  = "scip-go gomod example.com/hello v1.0.0 `example.com/hello/greet`/Name."
+	)
+	src := testSCIPSource
+	rng := func(after, s string) []int32 {
+		line, char := scipPos(t, src, after, s, enc)
+		return []int32{line, char, char + int32(len(s))}
+	}
+	helloLine, _ := scipPos(t, src, "", "func Hello", enc)
+	_, helloEnd := scipPos(t, src, "func Hello", "\n", enc)
+	return &scip.Index{
+		Documents: []*scip.Document{{
+			RelativePath:     "greet/greet.go",
+			PositionEncoding: scipEnc,
+			Occurrences: []*scip.Occurrence{
+				{
+					Range:          rng("func ", "Hello"),
+					Symbol:         hello,
+					SymbolRoles:    int32(scip.SymbolRole_Definition),
+					EnclosingRange: []int32{helloLine, 0, helloEnd},
+				},
+				{Range: rng("世界 \" + ", "Name"), Symbol: name},
+				{Range: rng("var ", "Name"), Symbol: name, SymbolRoles: int32(scip.SymbolRole_Definition)},
+				// Locals aren't refs.
+				{Range: rng("var ", "Name"), Symbol: "local 0"},
+			},
+			Symbols: []*scip.SymbolInformation{
+				{Symbol: hello, Documentation: []string{"```go\nfunc Hello() string\n```", "Hello says hello."}},
+			},
+		}},
+	}
+}
+
+func TestSCIPAnalysis(t *testing.T) {
+	dir := testDir(t, map[string]string{"greet/greet.go": testSCIPSource})
+	src := testSCIPSource
+	for _, enc := range []struct {
+		name    string
+		enc     posEncoding
+		scipEnc scip.PositionEncoding
+	}{
+		// Indexers from before the field was added count
+		// bytes.
+		{"unspecified", utf8Units, scip.PositionEncoding_UnspecifiedPositionEncoding},
+		{"UTF-8", utf8Units, scip.PositionEncoding_UTF8CodeUnitOffsetFromLineStart},
+		{"UTF-16", utf16Units, scip.PositionEncoding_UTF16CodeUnitOffsetFromLineStart},
+		{"UTF-32", utf32Units, scip.PositionEncoding_UTF32CodeUnitOffsetFromLineStart},
+	} {
+		t.Run(enc.name, func(t *testing.T) {
+			g := testGenerator(t, Options{Dir: dir})
+			a := g.scipAnalysis(dir, testSCIPIndex(t, enc.enc, enc.scipEnc))
+			if strings.Join(a.files, ",") != "greet/greet.go" {
+				t.Fatalf("got files %v, want greet/greet.go", a.files)
+			}
+
+			var refs []string
+			for _, r := range a.refs["greet/greet.go"] {
+				refs = append(refs, r.DefPath+"@"+wordAt(src, r.Start))
+			}
+			want := "example.com/hello/greet/Hello@Hello,example.com/hello/greet/Name@Name,example.com/hello/greet/Name@Name"
+			if got := strings.Join(refs, ","); got != want {
+				t.Errorf("got refs %s, want %s", got, want)
+			}
+			if r := a.refs["greet/greet.go"][1]; r.Start != offset(t, src, "世界 \" + ", "Name") {
+				t.Errorf("the ref after the non-ASCII text is at %d, want %d", r.Start, offset(t, src, "世界 \" + ", "Name"))
+			}
+
+			ds := a.defs["greet/greet.go"]
+			if len(ds) != 2 {
+				t.Fatalf("got defs %v, want Hello and Name", ds)
+			}
+			hello := ds[0]
+			if hello.Unit != "example.com/hello" || hello.Name != "Hello" || hello.Kind != "method" {
+				t.Errorf("got def %+v for Hello", hello)
+			}
+			if decl := src[hello.DefStart:hello.DefEnd]; !strings.HasPrefix(decl, "func Hello") || !strings.HasSuffix(decl, "Name }") {
+				t.Errorf("Hello covers %q, want its whole declaration", decl)
+			}
+			if hello.Signature != "func Hello() string" || hello.Doc != "Hello says hello." {
+				t.Errorf("Hello has signature %q and doc %q", hello.Signature, hello.Doc)
+			}
+			if name := ds[1]; name.Kind != "var" || src[name.DefStart:name.DefEnd] != "Name" {
+				t.Errorf("got def %+v for Name", name)
+			}
+			if docs := a.docs["greet/greet.go"]; len(docs) != 1 || !strings.Contains(docs[0].Data, "Hello says hello.") {
+				t.Errorf("got docs %v, want Hello's", docs)
+			}
+		})
+	}
+}
+
+func TestSCIPDefKey(t *testing.T) {
+	for _, test := range []struct {
+		symbol, unit, path, name, kind string
+		ok                             bool
+	}{
+		{"scip-go gomod github.com/a/b v1.0 `github.com/a/b/c`/T#M().", "github.com/a/b", "github.com/a/b/c/T/M", "M", "method", true},
+		{"scip-typescript npm . . src/`a.ts`/A#", "scip-typescript", "src/a.ts/A", "A", "type", true},
+		{"scip-go gomod github.com/a/b v1.0 `github.com/a/b/c`/F().(x)", "", "", "", "", false},
+		{"scip-go gomod github.com/a/b v1.0 `github.com/a/b/c`/", "", "", "", "", false},
+	} {
+		key, name, kind, ok := scipDefKey(test.symbol)
+		if ok != test.ok || key.Unit != test.unit || key.Path != test.path || name != test.name || kind != test.kind {
+			t.Errorf("scipDefKey(%q) = %v, %q, %q, %v; want {%s %s}, %q, %q, %v",
+				test.symbol, key, name, kind, ok, test.unit, test.path, test.name, test.kind, test.ok)
+		}
+	}
+}
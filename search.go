@@ -0,0 +1,69 @@
+package srcco
+
+import (
+	"encoding/json"
+	"path"
+	"sort"
+)
+
+// Every page has a search box for finding defs by name. The search
+// runs in the browser (see srcco.js), so we write an index of every
+// def in the project next to the pages, and the pages fetch it the
+// first time you search.
+
+// symbolIndexName is the name of the symbol index in the output
+// directory.
+const symbolIndexName = "srcco-symbols.json"
+
+// A symbol is a def in the symbol index. Anchor is the id of the
+// def's anchor on the page for File (see ann), and File is relative
+// to the output directory, so the browser can put the two together
+// into a link from any page. The hover cards on links to defs come
+// from the symbol index too, which is what Sig and Doc are for.
+type symbol struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Unit   string `json:"unit"`
+	Path   string `json:"path"`
+	File   string `json:"file"`
+	Anchor string `json:"anchor"`
+	Sig    string `json:"sig,omitempty"`
+	Doc    string `json:"doc,omitempty"`
+}
+
+// writeSymbolIndex writes the symbol index for the defs in defsMap to
+// out. The symbols are sorted, so that the index only changes
+// when the defs do.
+func writeSymbolIndex(out Output, defsMap map[defKey]def) error {
+	syms := make([]symbol, 0, len(defsMap))
+	for _, d := range defsMap {
+		name := d.Name
+		if name == "" {
+			name = path.Base(d.Path)
+		}
+		syms = append(syms, symbol{
+			Name:   name,
+			Kind:   d.Kind,
+			Unit:   d.Unit,
+			Path:   d.Path,
+			File:   htmlFilename(d.File),
+			Anchor: d.id(),
+			Sig:    d.Signature,
+			Doc:    d.Doc,
+		})
+	}
+	sort.Slice(syms, func(i, j int) bool {
+		if syms[i].Name != syms[j].Name {
+			return syms[i].Name < syms[j].Name
+		}
+		if syms[i].Unit != syms[j].Unit {
+			return syms[i].Unit < syms[j].Unit
+		}
+		return syms[i].Path < syms[j].Path
+	})
+	b, err := json.Marshal(syms)
+	if err != nil {
+		return err
+	}
+	return out.WriteFile(symbolIndexName, b)
+}
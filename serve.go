@@ -0,0 +1,249 @@
+package srcco
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// "srcco serve" is for previewing the docs while you write them. It
+// generates the docs, serves them over HTTP, and then watches the
+// project: whenever a file changes, it runs genDocs again (which only
+// regenerates the pages that changed, thanks to the manifest) and
+// tells any browsers that are looking at the docs to reload.
+
+// reloadPath is where browsers listen for reloads. It's a stream of
+// server-sent events
+// (https://html.spec.whatwg.org/multipage/server-sent-events.html),
+// which is all we need, because the messages only go one way.
+const reloadPath = "/_srcco/reload"
+
+// reloadScript is added to the end of every page that we serve, so
+// that the page reloads itself when we regenerate the docs. We add
+// it when we serve the page rather than when we generate it, so the
+// docs on disk are the same as the ones "srcco" makes.
+const reloadScript = `<script>new EventSource("` + reloadPath + `").onmessage = function() { location.reload(); };</script>
+`
+
+// Serve generates the docs for the project in opts.Dir, serves them at
+// addr, and regenerates them whenever the project changes. It runs
+// until ctx is done or something goes wrong. opts.GitHubPages and
+// opts.Output are ignored: we always generate the docs in opts.OutDir.
+func Serve(ctx context.Context, opts Options, addr string) error {
+	opts.GitHubPages = false
+	opts.Output = nil
+	g, err := newGenerator(opts)
+	if err != nil {
+		return err
+	}
+	if archiveKind(g.OutDir) != "" {
+		return fmt.Errorf("can't serve the docs from an archive (%s); choose a directory with -out", g.OutDir)
+	}
+	sitePath := g.projectPath(g.OutDir)
+	// A typo in the code you're editing shouldn't stop the
+	// server, so we just log the errors from generating the docs.
+	if err := g.generate(ctx); err != nil {
+		g.logf("%v", err)
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if err := g.watchDirs(w, g.Dir, sitePath); err != nil {
+		return err
+	}
+	r := &reloader{g: g, clients: map[chan struct{}]bool{}}
+	go r.watch(ctx, w, sitePath)
+
+	mux := http.NewServeMux()
+	mux.Handle(reloadPath, r)
+	mux.Handle("/", docsHandler{sitePath})
+	s := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		s.Close()
+	}()
+	g.logf("Serving the docs for %s at http://%s/", g.Dir, httpAddr(addr))
+	if err := s.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return ctx.Err()
+}
+
+// httpAddr makes addresses like ":8080" clickable.
+func httpAddr(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		return "localhost" + addr
+	}
+	return addr
+}
+
+// watchDirs adds dir and all of the directories under it to w. fsnotify
+// doesn't watch directories recursively, so we have to add each one.
+// We skip the output directory (otherwise every rebuild would trigger
+// another one) and hidden directories like .git and .srclib-cache.
+func (g *generator) watchDirs(w *fsnotify.Watcher, dir, sitePath string) error {
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if ignoreChange(p, g.Dir, sitePath) {
+			return filepath.SkipDir
+		}
+		g.vLogf("Watching %s", p)
+		return w.Add(p)
+	})
+}
+
+// ignoreChange tells us whether we should ignore a change to the file
+// or directory at p.
+func ignoreChange(p, dir, sitePath string) bool {
+	if p == sitePath || strings.HasPrefix(p, sitePath+string(filepath.Separator)) {
+		return true
+	}
+	return p != dir && strings.HasPrefix(filepath.Base(p), ".")
+}
+
+// reloader regenerates the docs when the project changes, and tells
+// its clients when it has.
+type reloader struct {
+	g       *generator
+	mu      sync.Mutex
+	clients map[chan struct{}]bool
+}
+
+// watch waits for changes from w. Editors tend to touch a file
+// several times when they save it (and "git checkout" touches lots of
+// them), so we wait until things have been quiet for a moment before
+// we regenerate the docs.
+func (r *reloader) watch(ctx context.Context, w *fsnotify.Watcher, sitePath string) {
+	const quiet = 200 * time.Millisecond
+	g := r.g
+	timer := time.NewTimer(quiet)
+	timer.Stop()
+	for {
+		select {
+		case e, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if ignoreChange(e.Name, g.Dir, sitePath) {
+				continue
+			}
+			g.vLogf("Changed: %s", e)
+			// New directories need to be watched too.
+			if e.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(e.Name); err == nil && info.IsDir() {
+					if err := g.watchDirs(w, e.Name, sitePath); err != nil {
+						g.logf("%v", err)
+					}
+				}
+			}
+			timer.Reset(quiet)
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			g.logf("%v", err)
+		case <-timer.C:
+			g.logf("Regenerating docs...")
+			if err := g.generate(ctx); err != nil {
+				g.logf("%v", err)
+				continue
+			}
+			r.reload()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reload tells every client to reload. A client that already has a
+// reload waiting doesn't need another one.
+func (r *reloader) reload() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for c := range r.clients {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// ServeHTTP streams reloads to a browser until it goes away.
+func (r *reloader) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	c := make(chan struct{}, 1)
+	r.mu.Lock()
+	r.clients[c] = true
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.clients, c)
+		r.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	f.Flush()
+	for {
+		select {
+		case <-c:
+			fmt.Fprintf(w, "data: reload\n\n")
+			f.Flush()
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
+
+// docsHandler serves the files in sitePath, adding reloadScript to
+// the HTML pages.
+type docsHandler struct {
+	sitePath string
+}
+
+func (h docsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if path.Ext(req.URL.Path) != ".html" {
+		http.FileServer(http.Dir(h.sitePath)).ServeHTTP(w, req)
+		return
+	}
+	file := filepath.Join(h.sitePath, filepath.FromSlash(path.Clean("/"+req.URL.Path)))
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		http.NotFound(w, req)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if i := bytes.LastIndex(b, []byte("</body>")); i >= 0 {
+		b = append(b[:i:i], append([]byte(reloadScript), b[i:]...)...)
+	} else {
+		b = append(b, reloadScript...)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(b)
+}
@@ -10,7 +10,7 @@
 //
 // Installation:
 //
-//   $ go get sourcegraph.com/sourcegraph/srcco
+//   $ go get sourcegraph.com/sourcegraph/srcco/cmd/srcco
 //
 // And install srclib:
 //
@@ -22,16 +22,29 @@
 //   $ srcco .
 // If you want to host your docs on GitHub Pages, run:
 //   $ srcco -github-pages=true .
+// If your project is written in Go, you can skip installing srclib
+// and let srcco analyze it with go/types instead:
+//   $ srcco -backend=go .
+// Or, if you already have a SCIP or LSIF index for your project:
+//   $ srcco -index=index.scip .
+// To put the whole project on one page, with a chapter for each file:
+//   $ srcco -format=book -book-order=deps .
+// To preview your docs while you work on them, run:
+//   $ srcco serve .
+// and open http://localhost:8080/. The pages reload themselves
+// whenever you change a file. Run "srcco -help" for the rest of the
+// flags.
 //
+// The srcco command is a thin wrapper around this package, so you can
+// generate docs from your own programs too:
 //
-//   Usage: srcco [FLAGS] DIR
+//   err := srcco.Generate(ctx, srcco.Options{Dir: ".", Backend: "go"})
 //
-//   Generate documentation for the project at DIR.
+// Set Options.Output to write the docs somewhere other than a
+// directory. A MemOutput keeps them in memory:
 //
-//     -enable-sourcegraph=false: generate links to Sourcegraph.com for references to external (out of repo) definitions
-//     -github-pages=false: create docs in gh-pages branch
-//     -out="docs": the directory name for the output files
-//     -v=false: show verbose output
+//   var out srcco.MemOutput
+//   err := srcco.Generate(ctx, srcco.Options{Dir: ".", Backend: "go", Output: &out})
 //
 // I extended the Go srclib toolchain
 // (https://sourcegraph.com/sourcegraph/srclib-go) to add start and end ranges
@@ -51,13 +64,15 @@
 // - Java
 //
 // Patches welcome!
-package main
+package srcco
 
 import (
 	"bytes"
-	"encoding/json"
-	"flag"
+	"context"
+	"errors"
 	"fmt"
+	"html"
+	"html/template"
 	"io"
 	"io/ioutil"
 	"log"
@@ -65,61 +80,179 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
-	"text/template"
+	"sync"
 
 	"github.com/sourcegraph/annotate"
 	"github.com/sourcegraph/syntaxhighlight"
 )
 
-func init() {
-	flag.BoolVar(&verboseOpt, "v", false, "show verbose output")
-	flag.StringVar(&outDirOpt, "out", "docs", "the directory name for the output files")
-	flag.BoolVar(&gitHubPagesOpt, "github-pages", false, "create docs in gh-pages branch and push to GitHub")
-	flag.BoolVar(&enableSourcegraphLinksOpt, "enable-sourcegraph", false, "generate links to Sourcegraph.com for references to external (out of repo) definitions")
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: srcco [FLAGS] DIR\n")
-		fmt.Fprintf(os.Stderr, "Generate documentation for the project at DIR.\n")
-		fmt.Fprintf(os.Stderr, "For more information, see:\n")
-		fmt.Fprintf(os.Stderr, "\tsourcegraph.github.io/srcco\n")
-		flag.PrintDefaults()
-		os.Exit(2)
-	}
-}
-
-// We define our option flags here.
-var (
-	// verboseOpt tells srcco to print out debugging logs.
-	verboseOpt bool
-	// outDirOpt is the output directory for the generated
-	// documentation.
-	outDirOpt string
-	// gitHubPagesOpt tells srcco to generate the docs in the
-	// repository's "gh-pages" branch and push it to GitHub. If
-	// gitHubPagesOpt is true, outDirOpt is ignored.
-	gitHubPagesOpt bool
-	// enableSourcegraphLinksOpt tells srcco to generate links to
+// Options tells Generate what to document and how. The zero value of
+// each field (other than Dir) is a sensible default, except for Push,
+// which the srcco command turns on.
+type Options struct {
+	// Dir is the project directory. If it's empty, we use the
+	// current working directory.
+	Dir string
+	// OutDir is the output directory for the generated
+	// documentation, relative to Dir unless it's absolute. It
+	// defaults to "docs". If it ends in .tar.gz, .tgz or .zip,
+	// the docs go in an archive with that name instead (see
+	// archive.go).
+	OutDir string
+	// Output is where the docs go, if not in a directory. If it's
+	// set, OutDir is ignored; it can't be used with GitHubPages.
+	// A MemOutput keeps the docs in memory, which is handy for
+	// tests. If Output is an ArchiveOutput, it's up to you to
+	// Close it afterwards.
+	Output Output
+	// Backend chooses where srcco gets its defs, refs and docs
+	// from. "srclib" (the default) shells out to the src CLI,
+	// and "go" analyzes Go projects in-process (see
+	// gobackend.go), so you don't need srclib installed at all.
+	Backend string
+	// Index is the path of a SCIP or LSIF index that was built
+	// ahead of time (by CI, say). If it's set, we use the index
+	// for everything and don't run a backend at all. We warn
+	// about files that are newer than the index, going by their
+	// modification times, but we still use it for them; the defs,
+	// refs and docs that don't fit the source anymore are left
+	// out (see stale.go).
+	Index string
+	// EnableSourcegraphLinks tells srcco to generate links to
 	// Sourcegraph.com for references to external (out of repo)
 	// definitions.
-	enableSourcegraphLinksOpt bool
-)
+	EnableSourcegraphLinks bool
+	// Jobs is the number of files that srcco works on at the same
+	// time. It defaults to the number of CPUs.
+	Jobs int
+	// KeepGoing tells srcco not to stop at the first file that it
+	// can't generate. If a file's annotations don't fit its docs
+	// (which usually means the index is out of date), we generate
+	// it without them; if anything else goes wrong, we skip it.
+	// Either way, we list the problems at the end.
+	KeepGoing bool
+	// Format is the kind of docs to generate. "html" (the
+	// default) makes a page per file, "book" puts every file in
+	// one long page, index.html, with a chapter for each (see
+	// book.go), "markdown" makes a Markdown page per file (see
+	// mdformat.go), and "json" writes out the rows of each page
+	// as data, for other front ends (see jsonformat.go).
+	Format string
+	// BookOrder is the order of the chapters in a book. It
+	// defaults to the order of the file names. "deps" puts Go
+	// packages after the packages they import, and anything else
+	// is the path of a file (relative to Dir) that lists the
+	// files to put in the book, one per line.
+	BookOrder string
+	// TemplateDir is a directory (relative to Dir) with files to
+	// use in place of the built-in templates, stylesheet and
+	// script (see templates.go). The built-in ones fill in for
+	// the files that it doesn't have.
+	TemplateDir string
+	// Theme changes the colors of the pages. It's the name of a
+	// built-in theme ("light", "dark", "high-contrast" or
+	// "docco"), or the path of a stylesheet (relative to Dir) to
+	// use in place of the built-in srcco.css, or the one in
+	// TemplateDir. It defaults to srcco's own colors.
+	Theme string
+	// Force tells srcco to ignore the manifest from the last run
+	// (see manifest.go) and regenerate every page.
+	Force bool
+	// GitHubPages tells srcco to generate the docs in the
+	// repository's "gh-pages" branch and push it to GitHub. If
+	// GitHubPages is true, OutDir is ignored.
+	GitHubPages bool
+	// Remote is the git remote that we push the gh-pages branch
+	// to (and fetch it from, if we don't have it yet). It
+	// defaults to "origin".
+	Remote string
+	// Push tells srcco to push the gh-pages branch after
+	// committing to it. Without it, you can look the commit over
+	// and push it yourself.
+	Push bool
+	// DryRun tells srcco to work out what it would commit to
+	// gh-pages, but not to change the repository or the remote.
+	DryRun bool
+	// Verbose tells srcco to print out debugging logs.
+	Verbose bool
+	// Log is where srcco tells you what it's doing. If it's nil,
+	// srcco keeps quiet.
+	Log io.Writer
+}
+
+// A generator does the work for one call to Generate. Most of srcco's
+// functions are its methods, so that they can get at the options and
+// the logger without any global state: you can run as many
+// generators at once as you like.
+type generator struct {
+	Options
+	logger *log.Logger
+	// assets are the templates and files for the pages (see
+	// templates.go). genDocs loads them.
+	assets *assets
+}
+
+// newGenerator fills in the defaults for opts and turns Dir into an
+// absolute path.
+func newGenerator(opts Options) (*generator, error) {
+	if opts.Dir == "" {
+		opts.Dir = "."
+	}
+	dir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	opts.Dir = dir
+	if opts.OutDir == "" {
+		opts.OutDir = "docs"
+	}
+	if opts.Backend == "" {
+		opts.Backend = "srclib"
+	}
+	switch opts.Format {
+	case "":
+		opts.Format = "html"
+	case "html", "book", "markdown", "json":
+	default:
+		return nil, fmt.Errorf("unknown format %q (must be \"html\", \"book\", \"markdown\" or \"json\")", opts.Format)
+	}
+	if opts.Output != nil && opts.GitHubPages {
+		return nil, errors.New("can't publish to GitHub Pages with an Output")
+	}
+	if opts.Jobs < 1 {
+		opts.Jobs = runtime.NumCPU()
+	}
+	if opts.Remote == "" {
+		opts.Remote = "origin"
+	}
+	w := opts.Log
+	if w == nil {
+		w = ioutil.Discard
+	}
+	return &generator{Options: opts, logger: log.New(w, "", 0)}, nil
+}
 
-// The vLogger is used for verbose logging.
-var vLogger = log.New(os.Stderr, "", 0)
+// logf tells the user what we're doing.
+func (g *generator) logf(format string, v ...interface{}) {
+	g.logger.Printf(format, v...)
+}
 
-func vLogf(format string, v ...interface{}) {
-	if !verboseOpt {
+// vLogf and vLog are used for verbose logging.
+func (g *generator) vLogf(format string, v ...interface{}) {
+	if !g.Verbose {
 		return
 	}
-	vLogger.Printf(format, v...)
+	g.logger.Printf(format, v...)
 }
 
-func vLog(v ...interface{}) {
-	if !verboseOpt {
+func (g *generator) vLog(v ...interface{}) {
+	if !g.Verbose {
 		return
 	}
-	vLogger.Println(v...)
+	g.logger.Println(v...)
 }
 
 // Source units have lots of information associated with them, but we
@@ -150,121 +283,147 @@ func (f failedCmd) Error() string {
 	return fmt.Sprintf("command %v failed: %s", f.cmd, f.err)
 }
 
-// ensureSrclibExists is a hack to make sure that "src" is accessible
-// from the PATH.
-func ensureSrclibExists() error {
-	cmd := exec.Command("src", "version")
-	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
-	cmd.Stdout, cmd.Stderr = stdout, stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf(
-			"error with srclib: %v, %s, %s",
-			err,
-			stdout.String(),
-			stderr.String(),
-		)
-	}
-	return nil
-}
-
 // command takes a set of command line arguments and returns the cmd
 // object, stdout, and stderr for that command.
-func command(argv []string) (cmd *exec.Cmd, stdout *bytes.Buffer, stderr *bytes.Buffer) {
+func command(ctx context.Context, argv []string) (cmd *exec.Cmd, stdout *bytes.Buffer, stderr *bytes.Buffer) {
 	if len(argv) == 0 {
 		panic("command: argv must have at least one item")
 	}
-	cmd = exec.Command(argv[0], argv[1:]...)
+	cmd = exec.CommandContext(ctx, argv[0], argv[1:]...)
 	stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
 	cmd.Stdout, cmd.Stderr = stdout, stderr
 	return cmd, stdout, stderr
 }
 
-// execute is the function that does all of the work. It takes the
-// project directory as dir. If dir is the empty string, then the
-// current working directory is used.
-func execute(dir string) error {
-	// First, we check to make sure that srclib exists.
-	if err := ensureSrclibExists(); err != nil {
-		log.Fatal(err)
+// A provider gives srcco the results of analyzing a project: its
+// files, and the defs, refs and docs in each of them. File names are
+// always relative to the project root. srclibProvider (in srclib.go)
+// gets them from the src CLI, and goProject (in gobackend.go) gets
+// them by analyzing Go code in-process.
+type provider interface {
+	listFiles(ctx context.Context) ([]string, error)
+	listDefs(ctx context.Context, file string) ([]def, error)
+	listRefsAndDocs(ctx context.Context, file string) ([]ref, []doc, error)
+	// staleHint tells the user how to bring the analysis up to
+	// date when it doesn't match the source (see stale.go).
+	staleHint() string
+}
+
+// analysis is a provider whose results have all been computed up
+// front, like the Go backend's and those read from an index. It's
+// also handy as a fake provider when testing.
+type analysis struct {
+	files []string
+	// defs, refs and docs are keyed by file name.
+	defs map[string][]def
+	refs map[string][]ref
+	docs map[string][]doc
+	// hint is what staleHint returns.
+	hint string
+}
+
+var _ provider = (*analysis)(nil)
+
+func newAnalysis() *analysis {
+	return &analysis{
+		defs: map[string][]def{},
+		refs: map[string][]ref{},
+		docs: map[string][]doc{},
 	}
+}
 
-	// We need to get a list of all of the files that we want to
-	// generate. First, we need to turn dir into an absolute path.
-	if dir == "" {
-		d, err := os.Getwd()
-		if err != nil {
-			log.Fatal(err)
-		}
-		dir = d
-	} else {
-		d, err := filepath.Abs(dir)
-		if err != nil {
-			log.Fatal(err)
+func (a *analysis) listFiles(ctx context.Context) ([]string, error) {
+	return a.files, nil
+}
+
+func (a *analysis) listDefs(ctx context.Context, file string) ([]def, error) {
+	return a.defs[file], nil
+}
+
+func (a *analysis) listRefsAndDocs(ctx context.Context, file string) ([]ref, []doc, error) {
+	return a.refs[file], a.docs[file], nil
+}
+
+func (a *analysis) staleHint() string {
+	return a.hint
+}
+
+// newProvider creates the provider for the project. If Index is set,
+// we read the index; otherwise we use Backend.
+func (g *generator) newProvider(ctx context.Context) (provider, error) {
+	if g.Index != "" {
+		return g.loadIndex(g.Dir, g.Index)
+	}
+	switch g.Backend {
+	case "srclib":
+		if err := ensureSrclibExists(ctx); err != nil {
+			return nil, err
 		}
-		dir = d
+		return srclibProvider{g, g.Dir}, nil
+	case "go":
+		return g.loadGoProject(ctx, g.Dir)
 	}
-	// We could import sourcegraph.com/sourcegraph/srclib/src and
-	// call src.APIUnitsCmd.Execute, but I want to demonstrate how
-	// to use src's command line interface. Plus, the user needs
-	// to set up srclib with their toolchains after installing it,
-	// so it might confuse them if go get'ing srcco also
-	// downloaded srclib's repo.
-	argv := []string{"src", "api", "units", dir}
-	cmd, stdout, stderr := command(argv)
-	vLogf("Running %v", argv)
-	if err := cmd.Run(); err != nil {
-		log.Fatal(failedCmd{argv, []interface{}{err, stdout.String(), stderr.String()}})
+	return nil, fmt.Errorf("unknown backend %q (must be \"srclib\" or \"go\")", g.Backend)
+}
+
+// Generate is the function that does all of the work: it generates
+// the docs for the project in opts.Dir.
+func Generate(ctx context.Context, opts Options) error {
+	g, err := newGenerator(opts)
+	if err != nil {
+		return err
 	}
-	if stdout.Len() == 0 {
-		log.Fatal(failedCmd{argv, "no output"})
+	return g.generate(ctx)
+}
+
+func (g *generator) generate(ctx context.Context) error {
+	p, err := g.newProvider(ctx)
+	if err != nil {
+		return err
 	}
 	// Get all of the file names associated with this project.
-	var us units
-	if err := json.Unmarshal(stdout.Bytes(), &us); err != nil {
-		log.Fatal(err)
+	files, err := p.listFiles(ctx)
+	if err != nil {
+		return err
 	}
 	// If we haven't found any files, that means the user probably
 	// hasn't installed any srclib language toolchains.
 	// Short-circuit here if that's the case.
-	noFiles := true
-	for _, u := range us {
-		if len(u.Files) != 0 {
-			noFiles = false
-			break
-		}
+	_, srclib := p.(srclibProvider)
+	if len(files) == 0 && g.Index != "" {
+		return fmt.Errorf("%s does not have any documents in %s", g.Index, g.Dir)
 	}
-	if noFiles {
-		fmt.Fprintf(os.Stderr, "srclib could not find any files for this project.\n")
-		fmt.Fprintf(os.Stderr, "Have you installed any language toolchains?\n")
-		fmt.Fprintf(os.Stderr, "If not, run 'src toolchain install-std'.\n")
-		os.Exit(1)
+	if len(files) == 0 && !srclib {
+		return fmt.Errorf("could not find any Go packages in %s", g.Dir)
 	}
-	if gitHubPagesOpt {
-		out := ".git/srcco-tmp"
-		if err := genDocs(dir, out, us.collateFiles()); err != nil {
+	if len(files) == 0 {
+		return fmt.Errorf("srclib could not find any files for this project. " +
+			"Have you installed any language toolchains? " +
+			"If not, run 'src toolchain install-std'")
+	}
+	if g.GitHubPages {
+		// We generate the docs in a temporary directory, out
+		// of the way of the project (which might not even be
+		// at the top of the repository), and commit them from
+		// there.
+		sitePath, err := os.MkdirTemp("", "srcco-gh-pages-")
+		if err != nil {
 			return err
 		}
-		// We need to remove all srclib build data from the
-		// project directory in order to add the correct files
-		// in our gh-pages script (in "data/publish-gh-pages.sh").
-		argv := []string{"src", "build-data", "rm", "--all", "--local"}
-		cmd, stdout, stderr := command(argv)
-		cmd.Dir = dir
-		if err := cmd.Run(); err != nil {
-			return failedCmd{argv, []interface{}{err, stdout.String(), stderr.String()}}
-		}
-		// We pipe the gh-pages script into bash. Bash's "-s"
-		// option tells it to read from stdin.
-		argv = []string{"bash", "-s"}
-		cmd, stdout, stderr = command(argv)
-		cmd.Stdin = bytes.NewReader(ghPagesScript)
-		if err := cmd.Run(); err != nil {
-			return failedCmd{argv, []interface{}{err, stdout.String(), stderr.String()}}
+		defer os.RemoveAll(sitePath)
+		if err := g.genDocs(ctx, p, DirOutput(sitePath), files); err != nil {
+			return err
 		}
-		return nil
+		return g.publishGitHubPages(ctx, sitePath)
 	}
 	// If we aren't generating a gh-pages site, generate the docs normally.
-	return genDocs(dir, outDirOpt, us.collateFiles())
+	if g.Output != nil {
+		return g.genDocs(ctx, p, g.Output, files)
+	}
+	if kind := archiveKind(g.OutDir); kind != "" {
+		return g.genArchive(ctx, p, files, kind)
+	}
+	return g.genDocs(ctx, p, DirOutput(g.projectPath(g.OutDir)), files)
 }
 
 // doc represents a comment. srclib also gives us the definition a
@@ -318,6 +477,13 @@ type def struct {
 	// We only care about TreePath to create a structured table of
 	// contents.
 	TreePath string
+	// Signature and Doc go on the card that shows up when you
+	// hover over a link to the def: Signature is its declaration
+	// (like "func F(x int) error") and Doc is the first paragraph
+	// of its documentation, as plain text. srclib doesn't give us
+	// either, so they're only filled in by the other backends.
+	Signature string `json:"-"`
+	Doc       string `json:"-"`
 }
 
 type defKey struct {
@@ -325,200 +491,430 @@ type defKey struct {
 	Path string
 }
 
-func (d def) path() string {
-	return d.TreePath
+// id is the id of the def's anchor on its page, which links and the
+// JSON output use too. It's a URL fragment, so it always has forward
+// slashes, whatever the OS.
+func (k defKey) id() string {
+	return path.Join(k.Unit, k.Path)
 }
 
-type defs []def
+// sortDefs sorts ds by TreePath, which is the order that createOutline
+// wants them in. Defs with the same TreePath are sorted by where they
+// start and then by Path, so that the outline comes out the same from
+// run to run, whatever order the provider listed them in.
+func sortDefs(ds []def) {
+	sort.SliceStable(ds, func(i, j int) bool {
+		if ds[i].TreePath != ds[j].TreePath {
+			return ds[i].TreePath < ds[j].TreePath
+		}
+		if ds[i].DefStart != ds[j].DefStart {
+			return ds[i].DefStart < ds[j].DefStart
+		}
+		return ds[i].Path < ds[j].Path
+	})
+}
 
-func (d defs) Len() int           { return len(d) }
-func (d defs) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
-func (d defs) Less(i, j int) bool { return d[i].TreePath < d[j].TreePath }
+// A formatter turns the rendered pages into one of the formats (see
+// Format). genDocs does the work that they all share: it asks the
+// provider for the defs, renders each file, and hands the pages to the
+// formatter, -j files at a time. So page and reuse have to be safe to
+// call for different files at the same time.
+type formatter interface {
+	// start is called before any pages, with the defs for each of
+	// files.
+	start(files []string, fileDefs [][]def, defsMap map[defKey]def) error
+	// reuse reports whether the output for files[i], whose source
+	// is src, is already up to date, in which case we don't
+	// render it.
+	reuse(i int, src []byte) bool
+	// page is called with the rendered page for files[i].
+	page(i int, src []byte, pg *page) error
+	// finish is called after all of the pages. It writes whatever
+	// needs all of them, like the indexes.
+	finish() error
+}
 
-var _ sort.Interface = defs{}
+// newFormatter returns the formatter for Format, which writes to out.
+func (g *generator) newFormatter(out Output) formatter {
+	switch g.Format {
+	case "book":
+		return &bookFormatter{g: g, out: out}
+	case "markdown":
+		return &markdownFormatter{g: g, out: out}
+	case "json":
+		return &jsonFormatter{g: g, out: out}
+	}
+	return &htmlFormatter{g: g, out: out}
+}
 
-// genDocs generates a set of docs for the project at root for the
-// code in files, and it outputs the docs in the directory siteName
-// (which must be relative to root).
-func genDocs(root, siteName string, files []string) error {
-	vLog("Generating Docs")
-	sitePath := filepath.Join(root, siteName)
-	if err := os.MkdirAll(sitePath, 0755); err != nil {
-		log.Fatal(err)
+// genDocs generates a set of docs for the project for the code in
+// files, and it writes them to out. The defs, refs and docs for each
+// file come from p.
+func (g *generator) genDocs(ctx context.Context, p provider, out Output, files []string) error {
+	g.vLog("Generating Docs")
+	assets, err := g.loadAssets()
+	if err != nil {
+		return err
 	}
-	// structuredTOCs is a map from file name to html-formatted
-	// structured table of contents. This is created but ignored
-	// for now (the ui is in the works! Contact the author of
-	// srcco if you're interested in helping!)
-	structuredTOCs := map[string]string{}
-	// defsMap is a map from defKeys to defs. We use it to store
-	// all of the defs that exist in this project so we can
-	// quickly look them up. Ideally, we would use "src api
-	// describe", but that call is too slow right now because it
-	// doesn't hit the new, faster srclib backend... yet :)
-	defsMap := map[defKey]def{}
-	for _, f := range files {
-		// Grab all the defs.
-		argv := []string{"src", "api", "list", "--file", filepath.Join(root, f), "--no-refs", "--no-docs"}
-		cmd, stdout, stderr := command(argv)
-		vLog("Running", argv)
-		if err := cmd.Run(); err != nil {
-			return failedCmd{argv, []interface{}{err, stdout.String(), stderr.String()}}
-		}
-		var out struct{ Defs []def }
-		if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
-			log.Fatal(err)
-		}
-		for _, d := range out.Defs {
-			defsMap[d.defKey] = d
+	g.assets = assets
+	if g.Format == "book" {
+		if files, err = g.bookOrder(files); err != nil {
+			return err
 		}
-		// We create the table of contents for the defs here.
-		// We wrap the defs in an interface that exposes their
-		// TreePaths as Path(), so we can use
-		// createTableOfContents on files too. See the
-		// documentation on createTableOfContents for more
-		// info.
-		sort.Sort(defs(out.Defs))
-		structuredTOCs[f] = createTableOfContents(defsWrapPathers(defsTOCFilter(out.Defs)))
 	}
-	// The files are wrapped as Pathers (which have the method
-	// Path()) so that createTableOfContents can be used with defs
-	// too. See the documentation on createTableOfContents for more info.
-	fileTOC := createTableOfContents(filesWrapPathers(files))
+	fileDefs, staleDefs, defsMap, err := g.listAllDefs(ctx, p, files)
+	if err != nil {
+		return err
+	}
+	r := g.newFormatter(out)
+	if err := r.start(files, fileDefs, defsMap); err != nil {
+		return err
+	}
 
 	// Okay, this is where the real work gets done! We process the
-	// refs for each file and generate the HTML for the code views
-	// in this loop.
-	for _, f := range files {
-		vLog("Processing", f)
-		src, err := ioutil.ReadFile(filepath.Join(root, f))
-		if err != nil {
-			return err
+	// refs for each file and render it here, -j files at a time.
+	// Nothing below writes to defsMap, so the workers can share
+	// it.
+	problems := make([]error, len(files))
+	// An archive keeps the files in the order that we write them
+	// (see archive.go), so for one of those we hand the pages to
+	// the formatter in the order of files, even though we render
+	// them -j at a time: turn[i] is closed once everything before
+	// files[i] is done.
+	var turn []chan struct{}
+	if _, ok := out.(*ArchiveOutput); ok {
+		turn = make([]chan struct{}, len(files)+1)
+		for i := range turn {
+			turn[i] = make(chan struct{})
 		}
-		argv := []string{"src", "api", "list", "--file", filepath.Join(root, f), "--no-defs"}
-		cmd, stdout, stderr := command(argv)
-		vLog("Running", argv)
-		if err := cmd.Run(); err != nil {
-			return failedCmd{argv, []interface{}{err, stdout.String(), stderr.String()}}
-		}
-		out := struct {
-			Refs []ref
-			Docs []doc
-		}{}
-		if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
-			log.Fatal(err)
-		}
-
-		// We filter out nonunique comments here, and comments
-		// that don't have the format "text/html". I fixed a
-		// bug in the Go toolchain that was generating
-		// overlapping comments, so that may not be needed.
-		// We're adding more powerful API commands to take
-		// advantage of the new srclib backend, and I want to
-		// replace this logic with a srclib call when that's
-		// done.
-		seenHTMLDoc := map[struct{ start, end uint32 }]bool{}
-		var htmlDocs []doc
-		for _, d := range out.Docs {
-			if d.Format == "text/html" {
-				if !seenHTMLDoc[struct{ start, end uint32 }{d.Start, d.End}] {
-					htmlDocs = append(htmlDocs, d)
-					seenHTMLDoc[struct{ start, end uint32 }{d.Start, d.End}] = true
-				}
-			}
+		close(turn[0])
+	}
+	waitTurn := func(i int) error {
+		select {
+		case <-turn[i]:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
 		}
-		// We turn the refs into HTML annotations that can be
-		// applied to the source code.
-		sort.Sort(refs(out.Refs))
-		anns, err := ann(src, out.Refs, f, defsMap)
-		if err != nil {
-			return err
+	}
+	err = g.eachFile(ctx, files, problems, func(i int) error {
+		if turn != nil {
+			defer func() {
+				waitTurn(i)
+				close(turn[i+1])
+			}()
 		}
-		htmlFile := htmlFilename(f)
-		vLogf("Creating dir %s", filepath.Dir(filepath.Join(sitePath, htmlFile)))
-		if err := os.MkdirAll(filepath.Dir(filepath.Join(sitePath, htmlFile)), 0755); err != nil {
-			log.Fatal(err)
-		}
-		// Sort everything *again* just to be sure!
-		sort.Sort(docs(htmlDocs))
-		sort.Sort(annotations(anns))
-		// Now we create the segments, which have the type
-		// "segment". They are fed into the template.
-		s, err := createSegments(src, anns, htmlDocs)
+		f := files[i]
+		src, err := ioutil.ReadFile(filepath.Join(g.Dir, f))
 		if err != nil {
 			return err
 		}
-		vLogf("Creating file %s", filepath.Join(sitePath, htmlFile))
-		w, err := os.Create(filepath.Join(sitePath, htmlFile))
+		if r.reuse(i, src) {
+			g.vLog("Skipping", f, "(unchanged)")
+			return nil
+		}
+		g.vLog("Processing", f)
+		pg, err := g.renderPage(ctx, p, f, src, fileDefs[i], staleDefs[i], defsMap)
 		if err != nil {
 			return err
 		}
-		// After gathering all that data, we feed it into our template!
-		if err := codeTemplate.Execute(w, HTMLOutput{f, resourcePrefix(f), fileTOC, structuredTOCs[f], s}); err != nil {
-			return err
+		problems[i] = pg.problem
+		if turn != nil {
+			if err := waitTurn(i); err != nil {
+				return err
+			}
 		}
-	}
-	// We copy our resource files at the end.
-	if err := copyBytes(cssData, filepath.Join(sitePath, "srcco.css")); err != nil {
+		return r.page(i, src, pg)
+	})
+	if err != nil {
 		return err
 	}
-	if err := copyBytes(jsData, filepath.Join(sitePath, "srcco.js")); err != nil {
+	if err := r.finish(); err != nil {
 		return err
 	}
-	return nil
+	// Last of all, we sum up what went wrong, if anything did.
+	return g.reportProblems(problems)
 }
 
-// copyBytes is a helper function that copies b to file.
-func copyBytes(b []byte, file string) error {
-	w, err := os.Create(file)
+// listAllDefs asks p for the defs in each of files. It returns them
+// file by file, and in defsMap, which is a map from defKeys to defs.
+// We use defsMap to store all of the defs that exist in this project
+// so we can quickly look them up. Ideally, we would use "src api
+// describe", but that call is too slow right now because it doesn't
+// hit the new, faster srclib backend... yet :)
+//
+// If a file has changed since it was analyzed, some of its defs may
+// not fit it any more (see stale.go). We leave those out, so that
+// nothing links to them or puts an anchor where they were, and
+// staleDefs[i] counts the ones that we left out of files[i].
+func (g *generator) listAllDefs(ctx context.Context, p provider, files []string) (fileDefs [][]def, staleDefs []int, defsMap map[defKey]def, err error) {
+	// Asking for the defs can be slow (srclib runs a command per
+	// file), so we do it for -j files at a time. Each file's defs
+	// go in their own slot, and we add them to defsMap in file
+	// order afterwards, so that the output doesn't depend on
+	// which worker finishes first.
+	fileDefs = make([][]def, len(files))
+	staleDefs = make([]int, len(files))
+	err = parallel(ctx, g.Jobs, len(files), func(i int) error {
+		// Grab all the defs.
+		ds, err := p.listDefs(ctx, files[i])
+		if err != nil {
+			return err
+		}
+		// We read the source again when we render the page,
+		// but we need the defs for every file before we can
+		// render any of them. If we can't read it, we'll
+		// find out then.
+		if src, err := ioutil.ReadFile(filepath.Join(g.Dir, files[i])); err == nil {
+			ds, staleDefs[i] = fitDefs(src, ds)
+		}
+		fileDefs[i] = ds
+		return nil
+	})
 	if err != nil {
-		return err
+		return nil, nil, nil, err
+	}
+	defsMap = map[defKey]def{}
+	for i := range files {
+		for _, d := range fileDefs[i] {
+			defsMap[d.defKey] = d
+		}
 	}
-	defer w.Close()
+	return fileDefs, staleDefs, defsMap, nil
+}
 
-	// do the actual work
-	_, err = io.Copy(w, bytes.NewReader(b))
-	return err
+// A page is a file, rendered: the rows of docs and code that go on
+// its page, and what we learned along the way.
+type page struct {
+	segments []segment
+	// refs are the file's refs, minus any that don't fit the
+	// source.
+	refs []ref
+	// anns are the annotations on the code, from ann.
+	anns []codeAnn
+	// includes has the hash of each file that the page includes
+	// code from (see directives.go).
+	includes map[string]string
+	stale    staleness
+	// problem is set if the page had to make do without links
+	// (see KeepGoing).
+	problem error
 }
 
-// HTMLOutput is fed into our code view template.
-type HTMLOutput struct {
-	Title                     string
-	ResourcePrefix            string
-	FileTableOfContents       string
-	StructuredTableOfContents string
-	Segments                  []segment
-}
-
-// These files are read from a really clever Go library, go-bindata,
-// that takes resource files and packs them into the binary to make
-// them easier to distribute.
-var codeTemplate *template.Template
-var cssData []byte
-var jsData []byte
-var ghPagesScript []byte
-
-func init() {
-	r, err := Asset("data/view.html")
+// renderPage renders the file f, whose source is src and whose defs
+// are fileDefs. staleDefs is the number of its defs that didn't fit
+// (see listAllDefs). It's the part of generating a page that doesn't
+// depend on where the page is going, so that every format can share
+// it.
+func (g *generator) renderPage(ctx context.Context, p provider, f string, src []byte, fileDefs []def, staleDefs int, defsMap map[defKey]def) (*page, error) {
+	fileRefs, fileDocs, err := p.listRefsAndDocs(ctx, f)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	codeTemplate = template.Must(template.New("view.html").Parse(string(r)))
-	r, err = Asset("data/srcco.css")
-	if err != nil {
-		log.Fatal(err)
+	// If the analysis is out of date, we leave out the
+	// bits that don't fit, and we leave the file out of
+	// the manifest, so that we try again next time.
+	// listAllDefs has already left out the defs that
+	// don't fit.
+	fileRefs, fileDocs, stale := checkStale(src, fileRefs, fileDocs, defsMap)
+	stale.defs = staleDefs
+	if stale.stale() {
+		g.logf("warning: %s has changed since it was analyzed, so we left out %d refs, %d docs and %d defs that don't fit it. %s.",
+			f, stale.refs, stale.docs, stale.defs, p.staleHint())
+	}
+
+	// We filter out nonunique comments here, and comments
+	// that don't have the format "text/html" or
+	// "text/plain". I fixed a bug in the Go toolchain that
+	// was generating overlapping comments, so that may not
+	// be needed. We're adding more powerful API commands
+	// to take advantage of the new srclib backend, and I
+	// want to replace this logic with a srclib call when
+	// that's done.
+	type docRange struct{ start, end uint32 }
+	seenDoc := map[docRange]bool{}
+	var htmlDocs []doc
+	// Plain text docs are rendered as Markdown (see
+	// markdown.go). We prefer them to HTML for the same
+	// comment, like srclib's Go toolchain gives us, so that
+	// every comment is rendered the same way.
+	for _, d := range fileDocs {
+		if d.Format != "text/plain" || seenDoc[docRange{d.Start, d.End}] {
+			continue
+		}
+		h, err := markdownHTML(d.Data)
+		if err != nil {
+			return nil, err
+		}
+		d.Format, d.Data = "text/html", h
+		htmlDocs = append(htmlDocs, d)
+		seenDoc[docRange{d.Start, d.End}] = true
 	}
-	cssData = r
-	r, err = Asset("data/srcco.js")
+	for _, d := range fileDocs {
+		if d.Format == "text/html" && !seenDoc[docRange{d.Start, d.End}] {
+			htmlDocs = append(htmlDocs, d)
+			seenDoc[docRange{d.Start, d.End}] = true
+		}
+	}
+	// Comments can have any HTML in them, so we clean it
+	// up before it goes anywhere near the page.
+	for i := range htmlDocs {
+		htmlDocs[i].Data = sanitizeDocHTML(htmlDocs[i].Data)
+	}
+	// Then we follow the directives in the comments (see
+	// directives.go), which add to the docs and hide
+	// some of the code.
+	includes := map[string]string{}
+	htmlDocs, hidden := g.applyDirectives(f, src, htmlDocs, defsMap, includes)
+	// We turn the refs into HTML annotations that can be
+	// applied to the source code.
+	sort.Sort(refs(fileRefs))
+	cas, err := g.ann(src, fileRefs, f, defsMap)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	anns := annHTML(cas)
+	// Sort everything *again* just to be sure! The sort
+	// needs to be stable to keep the def anchors in the
+	// order ann put them in.
+	sort.Sort(docs(htmlDocs))
+	sort.Stable(annotations(anns))
+	// Now we create the segments, which have the type
+	// "segment". They are fed into the template.
+	var problem error
+	s, err := g.createSegments(f, src, anns, htmlDocs)
+	if fe, ok := err.(*FileError); ok && g.KeepGoing {
+		// The annotations don't line up with the
+		// docs, so the best we can do is the code and
+		// docs without any links or highlighting. We
+		// leave the file out of the manifest so that
+		// we try again next time.
+		problem = fe
+		s, err = g.createSegments(f, src, nil, htmlDocs)
 	}
-	jsData = r
-	r, err = Asset("data/publish-gh-pages.sh")
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	ghPagesScript = r
+	markHidden(s, hidden)
+	return &page{segments: s, refs: fileRefs, anns: cas, includes: includes, stale: stale, problem: problem}, nil
+}
+
+// eachFile calls fn for every file, -j files at a time. If KeepGoing
+// is set, a file that goes wrong doesn't stop the others: we note what
+// happened in problems and tell the user about them all at the end
+// (see reportProblems).
+func (g *generator) eachFile(ctx context.Context, files []string, problems []error, fn func(i int) error) error {
+	return parallel(ctx, g.Jobs, len(files), func(i int) error {
+		err := fn(i)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		if _, ok := err.(*FileError); !ok {
+			err = &FileError{File: files[i], Err: err}
+		}
+		if !g.KeepGoing {
+			return err
+		}
+		problems[i] = err
+		return nil
+	})
+}
+
+// reportProblems sums up what went wrong, if anything did. It's an
+// error if we had to skip any files.
+func (g *generator) reportProblems(problems []error) error {
+	var n, skipped int
+	for _, err := range problems {
+		if err == nil {
+			continue
+		}
+		if n == 0 {
+			g.logf("Some files had problems:")
+		}
+		n++
+		if fe, ok := err.(*FileError); ok && fe.End > 0 {
+			g.logf("  %v (generated without links)", err)
+		} else {
+			g.logf("  %v (skipped)", err)
+			skipped++
+		}
+	}
+	if skipped > 0 {
+		return &problemsError{skipped: skipped, total: len(problems)}
+	}
+	return nil
+}
+
+// A problemsError is what reportProblems returns when it had to skip
+// some of the files, but generated the rest.
+type problemsError struct {
+	skipped, total int
+}
+
+func (e *problemsError) Error() string {
+	return fmt.Sprintf("%d of %d files could not be generated", e.skipped, e.total)
+}
+
+// parallel calls fn for every i in [0, n), running at most jobs calls
+// at a time. If any of them fail, it returns the error for the
+// smallest i, so that we report the same error no matter how the
+// calls were scheduled. Once ctx is done, the calls that haven't
+// started yet fail with ctx's error.
+func parallel(ctx context.Context, jobs, n int, fn func(i int) error) error {
+	if jobs < 1 {
+		jobs = 1
+	}
+	errs := make([]error, n)
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for j := 0; j < jobs && j < n; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				errs[i] = fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HTMLOutput is fed into the templates for the code pages, and the
+// search and references pages (see templates.go). The template is an
+// html/template, so everything that's already HTML has the type
+// template.HTML: we build the tables of contents and the code
+// ourselves, and the docs are sanitized first (see sanitize.go).
+type HTMLOutput struct {
+	// Title is the path of the file, relative to the project,
+	// or the name of the page if it isn't for a file.
+	Title string
+	// ResourcePrefix takes you from the page to the root of the
+	// docs, like "../../" for the page for a/b/c.go. Put it in
+	// front of srcco.css, say, or any other page.
+	ResourcePrefix string
+	// FileTableOfContents is the list of every file, with links
+	// to their pages.
+	FileTableOfContents template.HTML
+	// StructuredTableOfContents is the outline of the defs in the
+	// file (see outline.go). It's empty if there aren't any, and
+	// on the pages that aren't for a file.
+	StructuredTableOfContents template.HTML
+	// Segments are the rows of the page, in order.
+	Segments []segment
 }
 
 type annotations []annotate.Annotation
@@ -590,18 +986,73 @@ func resourcePrefix(file string) string {
 	return prefix
 }
 
-// htmlFilename takes a file, prepends a resource prefix to it and
-// appends ".html".
+// htmlFilename takes a file and gives you the name of its page in the
+// output, which is the file's name with ".html" on the end. Links to
+// the page need a resource prefix (or see pageLink).
 func htmlFilename(filename string) string {
-	return filepath.Join(resourcePrefix(filename), filename+".html")
+	return filepath.ToSlash(filename) + ".html"
+}
+
+// pageLink is the link from the page for the file from to the page for
+// the file to, if the page for a file is its name with ext on the end.
+// It's empty if they're the same page.
+func pageLink(from, to, ext string) string {
+	if from == to {
+		return ""
+	}
+	rel, err := filepath.Rel(filepath.Dir(from), to)
+	if err != nil {
+		rel = to
+	}
+	return filepath.ToSlash(rel) + ext
+}
+
+// defHref is the link from the page for the file from to the def with
+// the anchor id in file. In a book, every def is on the same page. If
+// id is empty, the link is to the file itself.
+func (g *generator) defHref(from, file, id string) string {
+	var href string
+	switch g.Format {
+	case "book":
+		if id == "" {
+			return "#" + chapterID(file)
+		}
+		return "#" + id
+	case "markdown":
+		href = pageLink(from, file, ".md")
+	default:
+		href = pageLink(from, file, ".html")
+	}
+	if id == "" {
+		return href
+	}
+	return href + "#" + id
+}
+
+// A codeAnn is an annotation on the code: a token that the syntax
+// highlighter gave a class, and maybe a link to its def, or the anchor
+// for a def that starts there (in which case start and end are the
+// same). ann works them out, and annHTML turns them into HTML, so that
+// formats that don't want HTML (see jsonformat.go) can use them too.
+type codeAnn struct {
+	start, end int
+	class      string
+	// def is the anchor id of the def in the project that the
+	// token refers to, and defFile is the def's file.
+	def, defFile string
+	// href is the link for the token: to def, or to Sourcegraph
+	// for a def outside the project.
+	href string
+	// anchor is the id of the def that starts here.
+	anchor string
 }
 
 // ann is a function that takes a source file, a set of refs for that
 // source file, the file name, and a map of all the defs in the
 // repository, and creates a set of annotations that can be applied to
 // the source file.
-func ann(src []byte, refs []ref, filename string, defs map[defKey]def) ([]annotate.Annotation, error) {
-	vLog("Annotating", filename)
+func (g *generator) ann(src []byte, refs []ref, filename string, defs map[defKey]def) ([]codeAnn, error) {
+	g.vLog("Annotating", filename)
 	// Run the source code through a generic code syntax
 	// highlighter to identify language units (vars, functions,
 	// etc) and give them classes.
@@ -631,76 +1082,134 @@ func ann(src []byte, refs []ref, filename string, defs map[defKey]def) ([]annota
 	// Now we go through all of the annotations, and we add links
 	// to annotations that are sitting on top of refs, and that we
 	// have definitions for in our def map.
-	anns := make([]annotate.Annotation, 0, len(annotations))
+	anns := make([]codeAnn, 0, len(annotations))
 	for _, a := range annotations {
+		ca := codeAnn{start: a.Start, end: a.End, class: string(a.Left)}
 		r, found := refAt(uint32(a.Start))
 		if !found {
-			a.Left = []byte(fmt.Sprintf(`<span class="%s">`, string(a.Left)))
-			a.Right = []byte(`</span>`)
-			anns = append(anns, *a)
+			anns = append(anns, ca)
 			continue
 		}
 		if d, ok := defs[defKey{r.DefUnit, r.DefPath}]; ok {
-			id := filepath.Join(d.Unit, d.Path)
-			href := htmlFilename(d.File) + "#" + id
-			a.Left = []byte(fmt.Sprintf(
-				`<span class="%s"><a href="%s">`,
-				string(a.Left),
-				href,
-			))
-			a.Right = []byte(`</span></a>`)
-		} else {
+			ca.def = d.id()
+			ca.defFile = d.File
+			ca.href = g.defHref(filename, d.File, ca.def)
+		} else if g.EnableSourcegraphLinks && r.DefRepo != "" {
 			// TODO: move api to new backend (which obsoletes 'r.DefRepo != ""')
-			if enableSourcegraphLinksOpt && r.DefRepo != "" {
-				a.Left = []byte(fmt.Sprintf(`<span class="%s"><a href="%s">`,
-					string(a.Left),
-					"https://"+path.Join(
-						"sourcegraph.com",
-						r.DefRepo,
-						"."+r.DefUnitType,
-						r.DefUnit,
-						".def",
-						r.DefPath,
-					)))
-				a.Right = []byte(`</a></span>`)
-			} else {
-				a.Left = []byte(fmt.Sprintf(`<span class="%s">`, string(a.Left)))
-				a.Right = []byte(`</span>`)
-			}
+			ca.href = "https://" + path.Join(
+				"sourcegraph.com",
+				r.DefRepo,
+				"."+r.DefUnitType,
+				r.DefUnit,
+				".def",
+				r.DefPath,
+			)
 		}
-		anns = append(anns, *a)
+		anns = append(anns, ca)
 	}
 
 	// Now we go through all of the defs and mark them up with
 	// "invisible" anchor tags which only have an id associated
-	// with them so that we can jump to them.
+	// with them so that we can jump to them. We sort them first,
+	// because several defs can start at the same place (like
+	// "var a, b int"), and map order would shuffle their anchors
+	// around from run to run.
+	var fileDefs []def
 	for _, d := range defs {
-		if d.File != filename {
-			continue
+		// listAllDefs left out the defs that don't fit the
+		// source, but the file could have changed again since
+		// it looked.
+		if d.File == filename && int(d.DefStart) <= len(src) {
+			fileDefs = append(fileDefs, d)
 		}
-		a := annotate.Annotation{
-			Left:  []byte(fmt.Sprintf(`<span class="def" id="%s">`, filepath.Join(d.Unit, d.Path))),
-			Right: []byte("</span>"),
-			Start: int(d.DefStart),
-			End:   int(d.DefStart),
+	}
+	sort.Slice(fileDefs, func(i, j int) bool {
+		if fileDefs[i].DefStart != fileDefs[j].DefStart {
+			return fileDefs[i].DefStart < fileDefs[j].DefStart
 		}
-		anns = append(anns, a)
+		return fileDefs[i].Path < fileDefs[j].Path
+	})
+	for _, d := range fileDefs {
+		anns = append(anns, codeAnn{
+			start:  int(d.DefStart),
+			end:    int(d.DefStart),
+			anchor: d.id(),
+		})
 	}
 	return anns, nil
 }
 
-// A segment represents a row in the final output.
+// annHTML turns the annotations from ann into HTML annotations that
+// can be applied to the source code.
+func annHTML(cas []codeAnn) []annotate.Annotation {
+	anns := make([]annotate.Annotation, 0, len(cas))
+	for _, ca := range cas {
+		a := annotate.Annotation{Start: ca.start, End: ca.end}
+		switch {
+		case ca.anchor != "":
+			a.Left = []byte(fmt.Sprintf(`<span class="def" id="%s">`, template.HTMLEscapeString(ca.anchor)))
+			a.Right = []byte("</span>")
+		case ca.def != "":
+			// data-def tells srcco.js which def to show a
+			// hover card for.
+			a.Left = []byte(fmt.Sprintf(
+				`<span class="%s"><a href="%s" data-def="%s">`,
+				ca.class,
+				template.HTMLEscapeString(ca.href),
+				template.HTMLEscapeString(ca.def),
+			))
+			a.Right = []byte(`</a></span>`)
+		case ca.href != "":
+			a.Left = []byte(fmt.Sprintf(`<span class="%s"><a href="%s">`, ca.class, template.HTMLEscapeString(ca.href)))
+			a.Right = []byte(`</a></span>`)
+		default:
+			a.Left = []byte(fmt.Sprintf(`<span class="%s">`, ca.class))
+			a.Right = []byte(`</span>`)
+		}
+		anns = append(anns, a)
+	}
+	return anns
+}
+
+// A segment represents a row in the final output. start and end are
+// the offsets in the source where its code starts and ends. Hidden is
+// set for code that a srcco:hide directive hides.
 type segment struct {
-	DocHTML  string
-	CodeHTML string
+	// DocHTML is the doc next to the code, if there is one.
+	DocHTML template.HTML
+	// CodeHTML is the code, with its links and highlighting. It's
+	// empty if a directive took the code out of the page.
+	CodeHTML template.HTML
+	// Hidden is set for the code that srcco:hide hides until you
+	// click on it (see directives.go).
+	Hidden     bool
+	start, end int
+}
+
+// A FileError is what goes wrong when we generate the page for one
+// file. If we know which bit of the file caused the problem, Start
+// and End are its byte offsets.
+type FileError struct {
+	File       string
+	Start, End int
+	Err        error
 }
 
-// createSegments takes the source code, all of the annotations, and
-// the docs, and it interleaves them into segments, where docs only
-// appear in the DocHTML bits, and code in the CodeHTML parts. anns
-// and docs must be sorted.
-func createSegments(src []byte, anns []annotate.Annotation, docs []doc) ([]segment, error) {
-	vLog("Creating segments")
+func (e *FileError) Error() string {
+	if e.End > 0 {
+		return fmt.Sprintf("%s:#%d-%d: %v", e.File, e.Start, e.End, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.File, e.Err)
+}
+
+func (e *FileError) Unwrap() error { return e.Err }
+
+// createSegments takes the name of a file, its source code, all of the
+// annotations, and the docs, and it interleaves them into segments,
+// where docs only appear in the DocHTML bits, and code in the
+// CodeHTML parts. anns and docs must be sorted.
+func (g *generator) createSegments(file string, src []byte, anns []annotate.Annotation, docs []doc) ([]segment, error) {
+	g.vLog("Creating segments")
 	var segments []segment
 	var s segment
 	var lineComment bool
@@ -719,7 +1228,7 @@ func createSegments(src []byte, anns []annotate.Annotation, docs []doc) ([]segme
 			// true, that means we've already added that
 			// doc to DocHTML, so we shouldn't add it again.
 			if !lineComment {
-				s.DocHTML = docs[0].Data
+				s.DocHTML = template.HTML(docs[0].Data)
 			}
 			// After ignoring the first line
 			// comment, we are no longer in a line
@@ -765,27 +1274,35 @@ func createSegments(src []byte, anns []annotate.Annotation, docs []doc) ([]segme
 			}
 			if lineComment {
 				addSegment()
-				s.DocHTML = docs[0].Data
+				s.DocHTML = template.HTML(docs[0].Data)
 			}
 		}
 		// In this loop, we add all of the annotations to the
 		// CodeHTML part of our segment.
+		s.start = i
 		for i < runTo {
 			// If there are no annotations left, we can
 			// short-circuit this process by stuffing the
 			// rest of the source code into the CodeHTML
 			// block.
 			if len(anns) == 0 {
-				s.CodeHTML += template.HTMLEscapeString(string(src[i:runTo]))
+				s.CodeHTML += template.HTML(template.HTMLEscapeString(string(src[i:runTo])))
 				i = runTo
 				break
 			}
 			// We work on one annotation at a time.
 			a := anns[0]
 			// Add all the space between i and a.Start to the CodeHTML block
+			// (but not past runTo, because a doc can
+			// start on the space before an annotation,
+			// like the indentation of a directive).
 			if i < a.Start {
-				s.CodeHTML += template.HTMLEscapeString(string(src[i:a.Start]))
-				i = a.Start
+				end := a.Start
+				if end > runTo {
+					end = runTo
+				}
+				s.CodeHTML += template.HTML(template.HTMLEscapeString(string(src[i:end])))
+				i = end
 				// We continue so that the 'i < runTo'
 				// check happens again, because we may
 				// have reached runTo.
@@ -793,45 +1310,32 @@ func createSegments(src []byte, anns []annotate.Annotation, docs []doc) ([]segme
 			}
 			// If the annotation extends past the end of
 			// our run, the state of our program is messed
-			// up (usually means the srclib-cache hasn't
-			// been refreshed.)
+			// up. checkStale throws out most of the
+			// analysis that would do this, but a doc that
+			// moved to the middle of a token still can.
 			if a.End > runTo {
-				log.Println(a, string(src[a.Start:a.End]), runTo)
-				log.Fatal("createSegment: illegal state: a.End > runTo")
+				return nil, &FileError{
+					File:  file,
+					Start: a.Start,
+					End:   a.End,
+					Err:   fmt.Errorf("code %q runs into the doc at #%d (is the index out of date?)", src[a.Start:a.End], runTo),
+				}
 			}
 			// Now we add the annotation in full to the CodeHTML block.
-			s.CodeHTML += string(a.Left) +
+			s.CodeHTML += template.HTML(string(a.Left) +
 				template.HTMLEscapeString(string(src[a.Start:a.End])) +
-				string(a.Right)
+				string(a.Right))
 			// Advance i and anns.
 			i = a.End
 			anns = anns[1:]
 		}
 		// At the end of our loop, we add a segment.
+		s.end = i
 		addSegment()
 	}
 	return segments, nil
 }
 
-// And that's it! We set up the flags and start the program in our
-// main function.
-func main() {
-	log.SetFlags(log.Lshortfile)
-	flag.Parse()
-	args := flag.Args()
-	if len(args) == 0 {
-		fmt.Fprintf(os.Stderr, "error: must provide a root directory\n")
-		flag.Usage()
-	} else if len(args) > 1 {
-		fmt.Fprintf(os.Stderr, "error: too many args\n")
-		flag.Usage()
-	}
-	if err := execute(args[0]); err != nil {
-		log.Println(err)
-		os.Exit(1)
-	}
-}
-
 // Everything below is my work in progress table of contents stuff,
 // some of which you saw above. I think it's pretty interesting, but
 // it's loosely commented for now. If you want to help out, email the
@@ -860,14 +1364,6 @@ func defsTOCFilter(defs []def) []def {
 	return f
 }
 
-func defsWrapPathers(defs []def) []pather {
-	ps := make([]pather, 0, len(defs))
-	for _, d := range defs {
-		ps = append(ps, pather(d))
-	}
-	return ps
-}
-
 type file string
 
 func (f file) path() string { return string(f) }
@@ -880,30 +1376,29 @@ func filesWrapPathers(files []string) []pather {
 	return ps
 }
 
-func createTableOfContents(pathers []pather) string {
+// createTableOfContents creates the HTML for a tree of pathers. The
+// links in it start with prefix, which is the resource prefix of the
+// page that it's going on.
+func createTableOfContents(pathers []pather, prefix string) (string, error) {
 	nodes := map[string]*tocNode{}
 	nodes[""] = &tocNode{name: "/"}
 	if len(pathers) == 0 {
-		return ""
+		return "", nil
 	}
-	switch pathers[0].(type) {
-	case def:
-		nodes[""].name = "all defs"
-	case file:
-		nodes[""].name = "all files"
-	default:
-		log.Fatal("createTableOfContents: illegal state: unknown type for pathers[0]")
+	if _, ok := pathers[0].(file); !ok {
+		return "", fmt.Errorf("createTableOfContents: illegal state: unknown type %T for pathers[0]", pathers[0])
 	}
+	nodes[""].name = "all files"
 
-	getParent := func(i int, parts []string) *tocNode {
+	getParent := func(i int, parts []string) (*tocNode, error) {
 		if i == 0 {
-			return nodes[""]
+			return nodes[""], nil
 		}
 		parent := nodes[strings.Join(parts[0:i], "/")]
 		if parent == nil {
-			log.Fatal("createTOC: illegal state: parent == nil")
+			return nil, fmt.Errorf("createTableOfContents: illegal state: no parent for %s", strings.Join(parts, "/"))
 		}
-		return parent
+		return parent, nil
 	}
 	// We've created the head node and added it to our map. Now,
 	// we need to go through all of the pathers and add them to the
@@ -936,14 +1431,17 @@ func createTableOfContents(pathers []pather) string {
 			// the nodes map, and then we add it to its
 			// parent.
 			nodes[path] = &tocNode{name: name}
-			parent := getParent(i, parts)
+			parent, err := getParent(i, parts)
+			if err != nil {
+				return "", err
+			}
 			parent.nodes = append(parent.nodes, nodes[path])
 		}
 	}
 	for _, pather := range pathers {
 		if n, ok := nodes[pather.path()]; ok {
 			if n.data != nil {
-				log.Fatal("createTableOfContents: illegal state: n.data != nil")
+				return "", fmt.Errorf("createTableOfContents: illegal state: %s is in the table of contents twice", pather.path())
 			}
 			p := pather
 			n.data = &p
@@ -959,43 +1457,24 @@ func createTableOfContents(pathers []pather) string {
 			break
 		}
 	}
-	var patherToHTML func(p pather) string
-	switch pathers[0].(type) {
-	case def:
-		patherToHTML = func(p pather) string {
-			d := p.(def)
-			return fmt.Sprintf(`<div class="node-path"><a class="def" href="%s">%s</a> - %s</div>`,
-				htmlFilename(d.File)+"#"+filepath.Join(d.Unit, d.Path),
-				d.Name,
-				d.Kind,
-			)
-		}
-	case file:
-		patherToHTML = func(p pather) string {
-			f := string(p.(file))
-			return fmt.Sprintf(`<div class="node-path"><a class="file" href="%s">%s</a></div>`,
-				htmlFilename(f),
-				filepath.Base(f),
-			)
-		}
+	patherToHTML := func(p pather) string {
+		f := string(p.(file))
+		return fmt.Sprintf(`<div class="node-path"><a class="file" href="%s">%s</a></div>`,
+			html.EscapeString(prefix+htmlFilename(f)),
+			html.EscapeString(filepath.Base(f)),
+		)
 	}
 	var nodeLevel int
 	var nodeToHTML func(n tocNode) string
 	nodeToHTML = func(n tocNode) string {
 		title := fmt.Sprintf(`<div class="node" level=%d><div class="node-title"><i class="fa fa-angle-right carrot"></i> %s`,
 			nodeLevel,
-			n.name,
+			html.EscapeString(n.name),
 		)
 		nodeLevel++
 		if pather := n.data; pather != nil {
-			template := ` <a href="%s"><i class="fa fa-share-square-o"></i></a>`
-			switch p := (*pather).(type) {
-			case def:
-				title += " - " + p.Kind
-				title += fmt.Sprintf(template, htmlFilename(p.File)+"#"+filepath.Join(p.Unit, p.Path))
-			case file:
-				title += fmt.Sprintf(template, htmlFilename(string(p)), filepath.Base(string(p)))
-			}
+			f := string((*pather).(file))
+			title += fmt.Sprintf(` <a href="%s"><i class="fa fa-share-square-o"></i></a>`, html.EscapeString(prefix+htmlFilename(f)))
 		}
 		title += "</div>"
 		body := `<div class="node-body">`
@@ -1009,5 +1488,5 @@ func createTableOfContents(pathers []pather) string {
 		body += "</div></div>"
 		return title + "\n" + body
 	}
-	return nodeToHTML(*nodes[""])
+	return nodeToHTML(*nodes[""]), nil
 }
@@ -0,0 +1,393 @@
+package srcco
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// The tests generate docs for a little project, testSources, with an
+// analysis that we write by hand (see testAnalysis), into a MemOutput.
+// That way they don't need srclib, or anything on disk but the sources.
+
+var testSources = map[string]string{
+	"main.go": `// Command hello says hello.
+package main
+
+import "example.com/hello/greet"
+
+func main() { greet.Hello() }
+`,
+	"greet/greet.go": `// Package greet says hello.
+package greet
+
+// Hello prints a greeting.
+func Hello() { println(Greeting) }
+
+// Greeting is what Hello says.
+const Greeting = "hi"
+`,
+}
+
+// testDir writes srcs to a temporary directory and returns it.
+func testDir(t *testing.T, srcs map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for f, src := range srcs {
+		file := filepath.Join(dir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// offset returns the offset of the first s in src, after the first
+// after (if it isn't empty).
+func offset(t *testing.T, src, after, s string) uint32 {
+	t.Helper()
+	base := 0
+	if after != "" {
+		base = strings.Index(src, after)
+		if base < 0 {
+			t.Fatalf("no %q in %q", after, src)
+		}
+		base += len(after)
+	}
+	i := strings.Index(src[base:], s)
+	if i < 0 {
+		t.Fatalf("no %q after %q in %q", s, after, src)
+	}
+	return uint32(base + i)
+}
+
+// wordAt returns the identifier that starts at off in src.
+func wordAt(src string, off uint32) string {
+	w := src[off:]
+	if i := strings.IndexFunc(w, func(r rune) bool { return !isWordRune(r) }); i >= 0 {
+		w = w[:i]
+	}
+	return w
+}
+
+// testAnalysis is the analysis of testSources that a backend would
+// give us.
+func testAnalysis(t *testing.T) *analysis {
+	a := newAnalysis()
+	a.files = []string{"greet/greet.go", "main.go"}
+	a.hint = "Run the tests again"
+
+	main, greet := testSources["main.go"], testSources["greet/greet.go"]
+	defAt := func(f, src, unit, p, kind, decl string) def {
+		start := offset(t, src, "", decl)
+		return def{
+			defKey:   defKey{unit, p},
+			Name:     p,
+			Kind:     kind,
+			File:     f,
+			DefStart: start,
+			DefEnd:   start + uint32(len(decl)),
+			TreePath: p,
+		}
+	}
+	a.defs["main.go"] = []def{
+		defAt("main.go", main, "example.com/hello", "main", "func", "func main() { greet.Hello() }"),
+	}
+	a.defs["greet/greet.go"] = []def{
+		defAt("greet/greet.go", greet, "example.com/hello/greet", "Hello", "func", "func Hello() { println(Greeting) }"),
+		defAt("greet/greet.go", greet, "example.com/hello/greet", "Greeting", "const", `const Greeting = "hi"`),
+	}
+	a.refs["main.go"] = []ref{
+		{DefUnit: "example.com/hello", DefPath: "main", File: "main.go", Start: offset(t, main, "func ", "main")},
+		{DefUnit: "example.com/hello/greet", DefPath: "Hello", File: "main.go", Start: offset(t, main, "greet.", "Hello")},
+	}
+	a.refs["greet/greet.go"] = []ref{
+		{DefUnit: "example.com/hello/greet", DefPath: "Hello", File: "greet/greet.go", Start: offset(t, greet, "func ", "Hello")},
+		{DefUnit: "example.com/hello/greet", DefPath: "Greeting", File: "greet/greet.go", Start: offset(t, greet, "println(", "Greeting")},
+		{DefUnit: "example.com/hello/greet", DefPath: "Greeting", File: "greet/greet.go", Start: offset(t, greet, "const ", "Greeting")},
+	}
+	docAt := func(src, comment string) doc {
+		start := offset(t, src, "", comment)
+		return doc{
+			Format: "text/plain",
+			Data:   strings.TrimPrefix(comment, "// "),
+			Start:  start,
+			End:    start + uint32(len(comment)),
+		}
+	}
+	a.docs["main.go"] = []doc{
+		docAt(main, "// Command hello says hello."),
+	}
+	a.docs["greet/greet.go"] = []doc{
+		docAt(greet, "// Package greet says hello."),
+		docAt(greet, "// Hello prints a greeting."),
+		docAt(greet, "// Greeting is what Hello says."),
+	}
+	return a
+}
+
+// testGenerator returns a generator for the project in dir, which
+// logs to the test's log.
+func testGenerator(t *testing.T, opts Options) *generator {
+	t.Helper()
+	opts.Log = testLog{t}
+	g, err := newGenerator(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// testLog writes to a test's log.
+type testLog struct{ t *testing.T }
+
+func (l testLog) Write(b []byte) (int, error) {
+	l.t.Log(strings.TrimSuffix(string(b), "\n"))
+	return len(b), nil
+}
+
+// genTestDocs generates the docs for testSources in the format opts
+// asks for, and returns them.
+func genTestDocs(t *testing.T, opts Options) *MemOutput {
+	t.Helper()
+	opts.Dir = testDir(t, testSources)
+	g := testGenerator(t, opts)
+	a := testAnalysis(t)
+	var out MemOutput
+	if err := g.genDocs(context.Background(), a, &out, a.files); err != nil {
+		t.Fatal(err)
+	}
+	return &out
+}
+
+// readOutput returns the file name in out, as a string.
+func readOutput(t *testing.T, out fs.FS, name string) string {
+	t.Helper()
+	b, err := fs.ReadFile(out, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestGenDocsHTML(t *testing.T) {
+	out := genTestDocs(t, Options{})
+
+	want := []string{
+		"greet/greet.go.html",
+		"main.go.html",
+		"srcco-manifest.json",
+		"srcco-refs.html",
+		"srcco-refs/greet/greet.go.json",
+		"srcco-search.html",
+		"srcco-search.json",
+		"srcco-symbols.json",
+		"srcco.css",
+		"srcco.js",
+	}
+	if got := out.Files(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got files\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	main := readOutput(t, out, "main.go.html")
+	for _, s := range []string{
+		// The link to Hello, from the page at the top.
+		`href="greet/greet.go.html#example.com/hello/greet/Hello"`,
+		`<p>Command hello says hello.</p>`,
+		`href="srcco.css"`,
+		`data-file="main.go"`,
+	} {
+		if !strings.Contains(main, s) {
+			t.Errorf("main.go.html doesn't have %s:\n%s", s, main)
+		}
+	}
+	greet := readOutput(t, out, "greet/greet.go.html")
+	for _, s := range []string{
+		// The anchors for the defs, and the links to them on
+		// the same page.
+		`id="example.com/hello/greet/Hello"`,
+		`id="example.com/hello/greet/Greeting"`,
+		`href="#example.com/hello/greet/Greeting"`,
+		`<p>Hello prints a greeting.</p>`,
+		// The page is a directory down, so the links to the
+		// rest of the docs go up one.
+		`href="../srcco.css"`,
+		`href="../main.go.html"`,
+		`data-resource-prefix="../"`,
+		// The links close inside the spans they open in.
+		`data-def="example.com/hello/greet/Greeting">Greeting</a></span>`,
+	} {
+		if !strings.Contains(greet, s) {
+			t.Errorf("greet/greet.go.html doesn't have %s:\n%s", s, greet)
+		}
+	}
+
+	if strings.Contains(main+greet, "</span></a>") {
+		t.Errorf("a link closes after its span:\n%s\n%s", main, greet)
+	}
+
+	// The symbol index points at the pages from the top.
+	if syms := readOutput(t, out, symbolIndexName); !strings.Contains(syms, `"file":"greet/greet.go.html"`) {
+		t.Errorf("%s doesn't point at greet/greet.go.html:\n%s", symbolIndexName, syms)
+	}
+}
+
+func TestGenDocsReuse(t *testing.T) {
+	dir := testDir(t, testSources)
+	var log bytes.Buffer
+	g, err := newGenerator(Options{Dir: dir, Verbose: true, Log: &log})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := testAnalysis(t)
+	var out MemOutput
+	if err := g.genDocs(context.Background(), a, &out, a.files); err != nil {
+		t.Fatal(err)
+	}
+	first := readOutput(t, &out, "main.go.html")
+
+	// Nothing has changed, so the second time we skip every page.
+	log.Reset()
+	if err := g.genDocs(context.Background(), a, &out, a.files); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(log.String(), "Processing") {
+		t.Errorf("regenerated pages that hadn't changed:\n%s", log.String())
+	}
+	if got := readOutput(t, &out, "main.go.html"); got != first {
+		t.Errorf("main.go.html changed:\n%s", got)
+	}
+
+	// If a file changes, we regenerate its page, and only its
+	// page.
+	main := strings.Replace(testSources["main.go"], "says hello", "greets you", 1)
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(main), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a.docs["main.go"][0].Data = "Command hello greets you."
+	log.Reset()
+	if err := g.genDocs(context.Background(), a, &out, a.files); err != nil {
+		t.Fatal(err)
+	}
+	var processed []string
+	for _, line := range strings.Split(log.String(), "\n") {
+		if f := strings.TrimPrefix(line, "Processing "); f != line {
+			processed = append(processed, f)
+		}
+	}
+	sort.Strings(processed)
+	if strings.Join(processed, ",") != "main.go" {
+		t.Errorf("processed %v, want [main.go]", processed)
+	}
+	if got := readOutput(t, &out, "main.go.html"); !strings.Contains(got, "Command hello greets you.") {
+		t.Errorf("main.go.html wasn't regenerated:\n%s", got)
+	}
+}
+
+func TestGenDocsAssetsChanged(t *testing.T) {
+	dir := testDir(t, testSources)
+	var log bytes.Buffer
+	g, err := newGenerator(Options{Dir: dir, TemplateDir: "templates", Verbose: true, Log: &log})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "templates"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	a := testAnalysis(t)
+	var out MemOutput
+	if err := g.genDocs(context.Background(), a, &out, a.files); err != nil {
+		t.Fatal(err)
+	}
+
+	// The pages load srcco.js, so a new one regenerates every page,
+	// even though view.html is the same.
+	if err := ioutil.WriteFile(filepath.Join(dir, "templates", "srcco.js"), []byte("// new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	log.Reset()
+	if err := g.genDocs(context.Background(), a, &out, a.files); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range a.files {
+		if !strings.Contains(log.String(), "Processing "+f+"\n") {
+			t.Errorf("%s wasn't regenerated:\n%s", f, log.String())
+		}
+	}
+}
+
+func TestSortDefs(t *testing.T) {
+	ds := []def{
+		{defKey: defKey{Path: "b"}, TreePath: "T", DefStart: 5},
+		{defKey: defKey{Path: "a"}, TreePath: "T", DefStart: 5},
+		{defKey: defKey{Path: "c"}, TreePath: "T", DefStart: 1},
+		{defKey: defKey{Path: "d"}, TreePath: "S", DefStart: 9},
+	}
+	sortDefs(ds)
+	var got []string
+	for _, d := range ds {
+		got = append(got, d.Path)
+	}
+	if strings.Join(got, ",") != "d,c,a,b" {
+		t.Errorf("sorted defs to %v, want [d c a b]", got)
+	}
+}
+
+// When a backend gives us a comment as both plain text and HTML, we
+// render the plain text as Markdown, the same as every other comment.
+func TestGenDocsPrefersPlainDocs(t *testing.T) {
+	opts := Options{Dir: testDir(t, testSources)}
+	g := testGenerator(t, opts)
+	a := testAnalysis(t)
+	d := a.docs["main.go"][0]
+	d.Data = "Command hello says *hello*."
+	html := d
+	html.Format, html.Data = "text/html", "<p>Command hello says hello.</p>"
+	a.docs["main.go"] = []doc{html, d}
+	var out MemOutput
+	if err := g.genDocs(context.Background(), a, &out, a.files); err != nil {
+		t.Fatal(err)
+	}
+	if main := readOutput(t, &out, "main.go.html"); !strings.Contains(main, "<p>Command hello says <em>hello</em>.</p>") {
+		t.Errorf("main.go.html doesn't have the Markdown doc:\n%s", main)
+	}
+}
+
+func TestGenDocsRemovesStalePages(t *testing.T) {
+	dir := testDir(t, testSources)
+	g := testGenerator(t, Options{Dir: dir})
+	a := testAnalysis(t)
+	var out MemOutput
+	if err := g.genDocs(context.Background(), a, &out, a.files); err != nil {
+		t.Fatal(err)
+	}
+	// When a file goes away, so does its page.
+	a.files = []string{"main.go"}
+	if err := g.genDocs(context.Background(), a, &out, a.files); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat(&out, "greet/greet.go.html"); err == nil {
+		t.Errorf("greet/greet.go.html is still there: %v", out.Files())
+	}
+}
+
+func TestCreateTableOfContents(t *testing.T) {
+	toc, err := createTableOfContents(filesWrapPathers([]string{"a/b/c.go", "a/d.go", "e.go"}), "../")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`href="../a/b/c.go.html"`, `href="../a/d.go.html"`, `href="../e.go.html"`} {
+		if !strings.Contains(toc, s) {
+			t.Errorf("the table of contents doesn't have %s:\n%s", s, toc)
+		}
+	}
+}
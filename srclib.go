@@ -0,0 +1,93 @@
+package srcco
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+)
+
+// srclibProvider gets its analysis data from srclib's command line
+// interface, which means that the user needs to have "src" and a
+// toolchain for their language installed.
+type srclibProvider struct {
+	g *generator
+	// root is the absolute path of the project directory.
+	root string
+}
+
+var _ provider = srclibProvider{}
+
+// ensureSrclibExists is a hack to make sure that "src" is accessible
+// from the PATH.
+func ensureSrclibExists(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "src", "version")
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf(
+			"error with srclib: %v, %s, %s",
+			err,
+			stdout.String(),
+			stderr.String(),
+		)
+	}
+	return nil
+}
+
+// srclibJSON runs the src command in argv and decodes its output into
+// v. If ctx is done before the command is, we kill it. src prints
+// nothing at all for a file with nothing in it, so no output leaves v
+// empty.
+func (p srclibProvider) srclibJSON(ctx context.Context, argv []string, v interface{}) error {
+	cmd, stdout, stderr := command(ctx, argv)
+	p.g.vLog("Running", argv)
+	if err := cmd.Run(); err != nil {
+		return failedCmd{argv, []interface{}{err, stdout.String(), stderr.String()}}
+	}
+	if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return nil
+	}
+	return json.Unmarshal(stdout.Bytes(), v)
+}
+
+func (p srclibProvider) staleHint() string {
+	return "srclib's cache is probably out of date: delete .srclib-cache and run srcco again"
+}
+
+func (p srclibProvider) listFiles(ctx context.Context) ([]string, error) {
+	// We could import sourcegraph.com/sourcegraph/srclib/src and
+	// call src.APIUnitsCmd.Execute, but I want to demonstrate how
+	// to use src's command line interface. Plus, the user needs
+	// to set up srclib with their toolchains after installing it,
+	// so it might confuse them if go get'ing srcco also
+	// downloaded srclib's repo.
+	var us units
+	if err := p.srclibJSON(ctx, []string{"src", "api", "units", p.root}, &us); err != nil {
+		return nil, err
+	}
+	return us.collateFiles(), nil
+}
+
+func (p srclibProvider) listDefs(ctx context.Context, file string) ([]def, error) {
+	var out struct{ Defs []def }
+	argv := []string{"src", "api", "list", "--file", filepath.Join(p.root, file), "--no-refs", "--no-docs"}
+	if err := p.srclibJSON(ctx, argv, &out); err != nil {
+		return nil, err
+	}
+	return out.Defs, nil
+}
+
+func (p srclibProvider) listRefsAndDocs(ctx context.Context, file string) ([]ref, []doc, error) {
+	out := struct {
+		Refs []ref
+		Docs []doc
+	}{}
+	argv := []string{"src", "api", "list", "--file", filepath.Join(p.root, file), "--no-defs"}
+	if err := p.srclibJSON(ctx, argv, &out); err != nil {
+		return nil, nil, err
+	}
+	return out.Refs, out.Docs, nil
+}
@@ -0,0 +1,70 @@
+package srcco
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// fakeSrc puts a "src" command on the PATH that runs script (in sh).
+func fakeSrc(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake src is a shell script")
+	}
+	bin := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(bin, "src"), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestSrclibProvider(t *testing.T) {
+	fakeSrc(t, `
+case "$*" in
+*--no-refs*) echo '{"Defs":[{"UnitType":"GoPackage","Unit":"example.com/hello","Path":"main","Name":"main","File":"main.go"}]}' ;;
+*) echo '{"Refs":[{"DefUnit":"example.com/hello","DefPath":"main","File":"main.go","Start":5}]}' ;;
+esac
+`)
+	p := srclibProvider{testGenerator(t, Options{}), "/project"}
+	ctx := context.Background()
+	ds, err := p.listDefs(ctx, "main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ds) != 1 || ds[0].Path != "main" {
+		t.Errorf("got defs %v, want main", ds)
+	}
+	rs, docs, err := p.listRefsAndDocs(ctx, "main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 1 || rs[0].Start != 5 || len(docs) != 0 {
+		t.Errorf("got refs %v and docs %v, want one ref at 5", rs, docs)
+	}
+}
+
+// src prints nothing for a file without any defs in it, which is
+// fine.
+func TestSrclibProviderNoOutput(t *testing.T) {
+	fakeSrc(t, "exit 0\n")
+	p := srclibProvider{testGenerator(t, Options{}), "/project"}
+	ds, err := p.listDefs(context.Background(), "empty.go")
+	if err != nil || len(ds) != 0 {
+		t.Errorf("got defs %v and error %v, want neither", ds, err)
+	}
+}
+
+// When the context is done, so are the src commands.
+func TestSrclibProviderCanceled(t *testing.T) {
+	fakeSrc(t, "sleep 10\n")
+	p := srclibProvider{testGenerator(t, Options{}), "/project"}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.listDefs(ctx, "main.go"); err == nil {
+		t.Error("got no error after the context was canceled")
+	}
+}
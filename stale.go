@@ -0,0 +1,173 @@
+package srcco
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"text/scanner"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sourcegraph/syntaxhighlight"
+)
+
+// The defs, refs and docs that a provider gives us are only right for
+// the version of the source that it analyzed. If the file has changed
+// since then (because srclib's cache or the index is out of date),
+// the offsets point at the wrong things, and we'd generate links in
+// the middle of words, or docs that cut code in half. So before we
+// generate a page, we check the analysis against the source, throw
+// out whatever doesn't fit, and tell the user how to fix it.
+
+// staleness counts the defs, refs and docs in a file that don't fit
+// its source.
+type staleness struct {
+	defs, refs, docs int
+}
+
+func (s staleness) stale() bool {
+	return s.defs != 0 || s.refs != 0 || s.docs != 0
+}
+
+// checkStale checks the refs and docs for a file against its source,
+// src. It returns the ones that fit, and counts the ones that don't. A
+// ref doesn't fit if it's past the end of the file, if it starts in
+// the middle of a word or on a space, or if it doesn't start with the
+// name of the def it points to (when that's in defsMap), because refs
+// always start at the beginning of the name. A doc doesn't fit if it's
+// past the end of the file or if it doesn't start on a comment. (We
+// check the defs before this, in fitDefs.)
+func checkStale(src []byte, rs []ref, docs []doc, defsMap map[defKey]def) ([]ref, []doc, staleness) {
+	var s staleness
+	goodRefs := rs[:0:0]
+	for _, r := range rs {
+		if !refFits(src, int(r.Start), defsMap[defKey{r.DefUnit, r.DefPath}].Name) {
+			s.refs++
+			continue
+		}
+		goodRefs = append(goodRefs, r)
+	}
+	goodDocs := docs[:0:0]
+	var comments, strs []textRange
+	if len(docs) != 0 {
+		comments, strs = scanRanges(src)
+	}
+	for _, d := range docs {
+		if d.Start > d.End || int(d.End) > len(src) || !docFits(src, int(d.Start), comments, strs) {
+			s.docs++
+			continue
+		}
+		goodDocs = append(goodDocs, d)
+	}
+	return goodRefs, goodDocs, s
+}
+
+// fitDefs returns the defs in ds that fit src, and the number that
+// don't. Like a doc, a def doesn't fit if it's past the end of the
+// file (or ends before it starts).
+func fitDefs(src []byte, ds []def) ([]def, int) {
+	good := ds[:0:0]
+	for _, d := range ds {
+		if d.DefStart > d.DefEnd || int(d.DefEnd) > len(src) {
+			continue
+		}
+		good = append(good, d)
+	}
+	return good, len(ds) - len(good)
+}
+
+// refFits reports whether a ref at start fits src. name is the name of
+// the def that it points to, or "" if we don't know the def.
+func refFits(src []byte, start int, name string) bool {
+	if start >= len(src) {
+		return false
+	}
+	if name != "" && !bytes.HasPrefix(src[start:], []byte(name)) {
+		return false
+	}
+	r, _ := utf8.DecodeRune(src[start:])
+	if unicode.IsSpace(r) {
+		return false
+	}
+	if start == 0 {
+		return true
+	}
+	prev, _ := utf8.DecodeLastRune(src[:start])
+	return !(isWordRune(prev) && isWordRune(r))
+}
+
+// A textRange is the text from start up to end in a file.
+type textRange struct {
+	start, end int
+}
+
+// scanRanges returns where the comments and the string literals in
+// src are, according to the same scanner that highlights the code, in
+// order.
+func scanRanges(src []byte) (comments, strs []textRange) {
+	sc := syntaxhighlight.NewScanner(src)
+	for tok := sc.Scan(); tok != scanner.EOF; tok = sc.Scan() {
+		start := sc.Position.Offset
+		r := textRange{start, start + len(sc.TokenText())}
+		switch tok {
+		case scanner.Comment:
+			comments = append(comments, r)
+		case scanner.String, scanner.RawString, scanner.Char:
+			strs = append(strs, r)
+		}
+	}
+	return comments, strs
+}
+
+// inRanges reports whether off is in one of rs.
+func inRanges(off int, rs []textRange) bool {
+	for _, r := range rs {
+		if r.start <= off && off < r.end {
+			return true
+		}
+	}
+	return false
+}
+
+// commentMarkerRE matches the start of a comment in the languages
+// whose comments the scanner doesn't know about, like Python's and
+// Lisp's.
+var commentMarkerRE = regexp.MustCompile(`^(?:#|--|;|"""|''')`)
+
+// docFits reports whether a doc at start fits src: it has to start on
+// one of the comments, or at least on something that looks like one
+// and isn't in a string.
+func docFits(src []byte, start int, comments, strs []textRange) bool {
+	for _, c := range comments {
+		if c.start == start {
+			return true
+		}
+	}
+	return !inRanges(start, comments) && !inRanges(start, strs) && commentMarkerRE.Match(src[start:])
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// staleFiles returns the files in files (relative to dir) that have
+// been modified since the index at indexFile was built. It only
+// compares modification times, so it's just a hint: a file that was
+// touched but not changed shows up, and a file that was changed back
+// doesn't. loadIndex only warns about them; checkStale is what keeps
+// the pages right.
+func staleFiles(dir, indexFile string, files []string) []string {
+	fi, err := os.Stat(indexFile)
+	if err != nil {
+		return nil
+	}
+	var stale []string
+	for _, f := range files {
+		sfi, err := os.Stat(filepath.Join(dir, f))
+		if err == nil && sfi.ModTime().After(fi.ModTime()) {
+			stale = append(stale, f)
+		}
+	}
+	return stale
+}
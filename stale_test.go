@@ -0,0 +1,100 @@
+package srcco
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestFitDefs(t *testing.T) {
+	src := []byte("package p\n\nfunc F() {}\n")
+	ds := []def{
+		{defKey: defKey{"p", "F"}, DefStart: 11, DefEnd: 22},
+		// Past the end of the file.
+		{defKey: defKey{"p", "G"}, DefStart: 30, DefEnd: 40},
+		// Ends before it starts.
+		{defKey: defKey{"p", "H"}, DefStart: 20, DefEnd: 11},
+	}
+	good, stale := fitDefs(src, ds)
+	if len(good) != 1 || good[0].Path != "F" || stale != 2 {
+		t.Errorf("got %v and %d stale, want just F and 2 stale", good, stale)
+	}
+}
+
+func TestCheckStale(t *testing.T) {
+	src := []byte("package p\n\n// F does it.\nfunc F() { G() }\n\nvar s = \"# not a doc\"\n")
+	defsMap := map[defKey]def{
+		{"p", "F"}: {defKey: defKey{"p", "F"}, Name: "F"},
+		{"p", "G"}: {defKey: defKey{"p", "G"}, Name: "G"},
+	}
+	at := func(s string) uint32 { return uint32(bytes.Index(src, []byte(s))) }
+	rs := []ref{
+		{DefUnit: "p", DefPath: "F", Start: at("F()")},
+		{DefUnit: "p", DefPath: "G", Start: at("G()")},
+		// It starts on the start of a name, but not G's.
+		{DefUnit: "p", DefPath: "G", Start: at("F()")},
+		// We don't know the def, so any name will do.
+		{DefUnit: "q", DefPath: "H", Start: at("s =")},
+		// In the middle of a word.
+		{DefUnit: "q", DefPath: "H", Start: at("unc F")},
+	}
+	ds := []doc{
+		{Start: at("// F"), End: at("func F") - 1},
+		// It's not on a comment.
+		{Start: at("func F"), End: at("{ G")},
+		// It's on a string that looks like a comment.
+		{Start: at("# not"), End: at("# not") + 11},
+	}
+	goodRefs, goodDocs, s := checkStale(src, rs, ds, defsMap)
+	if len(goodRefs) != 3 || goodRefs[2].DefUnit != "q" || s.refs != 2 {
+		t.Errorf("got refs %v and %d stale, want the first, second and fourth and 2 stale", goodRefs, s.refs)
+	}
+	if len(goodDocs) != 1 || goodDocs[0].Start != at("// F") || s.docs != 2 {
+		t.Errorf("got docs %v and %d stale, want just the comment and 2 stale", goodDocs, s.docs)
+	}
+}
+
+// When a file has been cut short since it was analyzed, the defs, refs
+// and docs past its new end don't fit it any more. We should leave
+// them out of the page (and out of everything else), rather than
+// putting anchors past the end of the code.
+func TestGenDocsTruncatedSource(t *testing.T) {
+	greet := testSources["greet/greet.go"]
+	srcs := map[string]string{
+		"main.go":        testSources["main.go"],
+		"greet/greet.go": greet[:strings.Index(greet, "// Greeting")],
+	}
+	var log bytes.Buffer
+	g, err := newGenerator(Options{Dir: testDir(t, srcs), Log: &log})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := testAnalysis(t)
+	var out MemOutput
+	if err := g.genDocs(context.Background(), a, &out, a.files); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(log.String(), "greet/greet.go has changed since it was analyzed, so we left out 1 refs, 1 docs and 1 defs") {
+		t.Errorf("didn't warn about the stale analysis:\n%s", log.String())
+	}
+	page := readOutput(t, &out, "greet/greet.go.html")
+	if strings.Contains(page, "example.com/hello/greet/Greeting") {
+		t.Errorf("greet/greet.go.html still has Greeting in it:\n%s", page)
+	}
+	if !strings.Contains(page, `id="example.com/hello/greet/Hello"`) {
+		t.Errorf("greet/greet.go.html lost the anchor for Hello:\n%s", page)
+	}
+	if syms := readOutput(t, &out, symbolIndexName); strings.Contains(syms, `"Greeting"`) {
+		t.Errorf("%s still has Greeting in it:\n%s", symbolIndexName, syms)
+	}
+	// The page for main.go is fine, and still links to Hello.
+	if main := readOutput(t, &out, "main.go.html"); !strings.Contains(main, `href="greet/greet.go.html#example.com/hello/greet/Hello"`) {
+		t.Errorf("main.go.html doesn't link to Hello:\n%s", main)
+	}
+	// We try the stale page again next time.
+	if m := readOutput(t, &out, manifestName); strings.Contains(m, `"greet/greet.go"`) {
+		t.Errorf("greet/greet.go is in the manifest:\n%s", m)
+	}
+}
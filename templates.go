@@ -0,0 +1,209 @@
+package srcco
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The pages are made from a handful of files: the templates for the
+// code pages (view.html), the search and references pages (search.html
+// and refs.html) and the book (book.html), and the stylesheet and
+// script that they all load (srcco.css and srcco.js). They're built
+// into srcco (they're in data), but TemplateDir can replace any of
+// them, so you can make the docs look however you like.
+//
+// Theme changes the colors. The built-in themes (see themes) are
+// stylesheets in data/themes that we add to the end of srcco.css, so
+// they only have to say what's different, including a color for each
+// of the classes that the syntax highlighting uses (see htmlAnnotator).
+// Theme can also be the path of a stylesheet, which replaces srcco.css
+// altogether.
+//
+// A template gets an HTMLOutput (or, for book.html, a BookOutput),
+// whose fields are a contract: we won't take them away or change what
+// they mean, so your templates will keep working. It can use the
+// functions in templateFuncs, as well as the ones that html/template
+// has. srcco.js looks for a few things in the page, like the
+// data-resource-prefix attribute on the body, the "search" input, and
+// the rows' ids and their "doc" and "code" cells (which the full-text
+// search reads the lines from), so if you replace view.html but keep
+// srcco.js, start from the built-in view.html.
+
+// assetNames are the names of the files that TemplateDir can replace.
+var assetNames = []string{"view.html", "search.html", "refs.html", "book.html", "srcco.css", "srcco.js"}
+
+// assets are the files that we make the pages from.
+type assets struct {
+	code, search, backlinks, book *template.Template
+	css, js                       []byte
+	// hash is the hash of every file in assetNames, theme and
+	// all. It goes in the manifest, so that a change to any of
+	// them regenerates every page.
+	hash string
+}
+
+// templateFuncs are the functions that the templates can use, on top
+// of html/template's.
+var templateFuncs = template.FuncMap{
+	// base, dir and ext are path.Base, path.Dir and path.Ext, for
+	// slash-separated paths like .Title.
+	"base": path.Base,
+	"dir":  path.Dir,
+	"ext":  path.Ext,
+	// pageName is the name of the page for a file, relative to
+	// the root of the docs. Put .ResourcePrefix in front of it to
+	// link to the page.
+	"pageName":  htmlFilename,
+	"lower":     strings.ToLower,
+	"upper":     strings.ToUpper,
+	"hasPrefix": strings.HasPrefix,
+	"hasSuffix": strings.HasSuffix,
+	// add adds two numbers, so that you can number the rows from
+	// one, say: {{add $i 1}}.
+	"add": func(a, b int) int { return a + b },
+}
+
+// dataFS holds the files that are built into srcco.
+//
+//go:embed data
+var dataFS embed.FS
+
+// themes are the names of the built-in themes.
+var themes = []string{"light", "dark", "high-contrast", "docco"}
+
+// builtinTheme reports whether name is one of themes.
+func builtinTheme(name string) bool {
+	for _, t := range themes {
+		if name == t {
+			return true
+		}
+	}
+	return false
+}
+
+// quoted returns the strings in ss in quotes.
+func quoted(ss []string) []string {
+	q := make([]string, len(ss))
+	for i, s := range ss {
+		q[i] = strconv.Quote(s)
+	}
+	return q
+}
+
+// readData reads the built-in file name.
+func readData(name string) ([]byte, error) {
+	return dataFS.ReadFile("data/" + name)
+}
+
+// defaultAssets are the files that are built into srcco.
+var defaultAssets *assets
+
+func init() {
+	a, err := newAssets(readData)
+	if err != nil {
+		panic(err)
+	}
+	defaultAssets = a
+}
+
+// newAssets parses the files that read returns for each of
+// assetNames.
+func newAssets(read func(name string) ([]byte, error)) (*assets, error) {
+	files := map[string][]byte{}
+	var all [][]byte
+	for _, name := range assetNames {
+		b, err := read(name)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = b
+		all = append(all, []byte(name), []byte{0}, b, []byte{0})
+	}
+	a := &assets{css: files["srcco.css"], js: files["srcco.js"], hash: hashBytes(all...)}
+	for _, t := range []struct {
+		name string
+		t    **template.Template
+	}{
+		{"view.html", &a.code},
+		{"search.html", &a.search},
+		{"refs.html", &a.backlinks},
+		{"book.html", &a.book},
+	} {
+		var err error
+		if *t.t, err = template.New(t.name).Funcs(templateFuncs).Parse(string(files[t.name])); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// loadAssets returns the files to make the pages from: the ones in
+// TemplateDir, if it has them, and the built-in ones otherwise, with
+// Theme applied to srcco.css, if it's set. We load them for every run,
+// so that "srcco serve" picks up changes to them.
+func (g *generator) loadAssets() (*assets, error) {
+	if g.TemplateDir == "" && g.Theme == "" {
+		return defaultAssets, nil
+	}
+	dir, theme := g.projectPath(g.TemplateDir), ""
+	if !builtinTheme(g.Theme) {
+		theme = g.projectPath(g.Theme)
+	}
+	if dir != "" {
+		if fi, err := os.Stat(dir); err != nil {
+			return nil, err
+		} else if !fi.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", g.TemplateDir)
+		}
+	}
+	read := func(name string) ([]byte, error) {
+		if dir != "" {
+			b, err := ioutil.ReadFile(filepath.Join(dir, name))
+			if err == nil {
+				g.vLogf("Using %s from %s", name, g.TemplateDir)
+				return b, nil
+			}
+			if !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+		return readData(name)
+	}
+	return newAssets(func(name string) ([]byte, error) {
+		if name != "srcco.css" || g.Theme == "" {
+			return read(name)
+		}
+		if theme != "" {
+			b, err := ioutil.ReadFile(theme)
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("unknown theme %q (must be %s, or a stylesheet)", g.Theme, strings.Join(quoted(themes), ", "))
+			}
+			return b, err
+		}
+		css, err := read(name)
+		if err != nil {
+			return nil, err
+		}
+		t, err := readData("themes/" + g.Theme + ".css")
+		if err != nil {
+			return nil, err
+		}
+		return append(append(css, '\n'), t...), nil
+	})
+}
+
+// projectPath returns the path of the file name, which is relative to
+// Dir unless it's absolute. It returns "" for "".
+func (g *generator) projectPath(name string) string {
+	if name == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(g.Dir, name)
+}
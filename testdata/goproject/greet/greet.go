@@ -0,0 +1,13 @@
+// Package greet says hello.
+package greet
+
+// Greeter greets someone.
+type Greeter struct {
+	Name string // who to greet
+}
+
+// Greet returns the greeting.
+func (g Greeter) Greet() string { return Hello + ", " + g.Name }
+
+// Hello is what a Greeter says.
+const Hello = "hello"
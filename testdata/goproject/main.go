@@ -0,0 +1,13 @@
+// Command hello says hello.
+package main
+
+import (
+	"fmt"
+
+	"example.com/hello/greet"
+)
+
+func main() {
+	g := greet.Greeter{Name: "world"}
+	fmt.Println(g.Greet()) // Say it.
+}